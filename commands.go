@@ -0,0 +1,816 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/beckbria/dirnum/dirnum"
+)
+
+// command is a dirnum subcommand.  run parses the arguments following the command name and returns the exit code.
+type command struct {
+	name, summary string
+	run           func(args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"validate", "Check that file names are well-numbered, exiting non-zero if not", runValidate},
+		{"baseline", "Record the current validation errors as accepted", runBaseline},
+		{"renumber", "Fill gaps in the major numbering and renumber minor versions", runRenumber},
+		{"fix", "Renumber minor versions and normalize names without moving major groups", runFix},
+		{"append", "Append one group onto another, at any level", runAppend},
+		{"split", "Move a group, at any level, to an unused version", runSplit},
+		{"migrate", "Rename files to use a different descriptor style", runMigrate},
+		{"apply", "Apply a plan file written by -save-plan, unless the files it renames have changed", runApply},
+		{"undo", "Reverse the most recent operation which renamed files", runUndo},
+		{"dupes", "Find files with identical contents and optionally move the extras to a trash folder", runDupes},
+		{"similar", "Find visually similar images using perceptual hashes", runSimilar},
+		{"sheet", "Render contact sheets of thumbnails for the directory or each major group", runSheet},
+		{"manifest", "Record the SHA-256 checksum of every file for later verification", runManifest},
+		{"verify", "Check files against the checksum manifest", runVerify},
+		{"export", "Copy files into subdirectories based on their tags", runExportCommand},
+		{"stats", "Count how often each tag is used", runStats},
+		{"serve", "Browse the directory and apply plans from a web page on localhost", runServe},
+		{"config", "Print the effective configuration", runConfig},
+		{"help", "Describe a command", runHelp},
+	}
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// printCommands lists the available commands
+func printCommands() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags] <dir>\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s help <command>' for the flags of a command.\n", os.Args[0])
+}
+
+// newFlagSet creates the flag set for a command, with usage text built from the command's summary
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags] <dir>\n\n%s.\n\nFlags:\n", os.Args[0], name, findCommand(name).summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// renameFlags are the flags shared by commands which rename files
+type renameFlags struct {
+	yes, dryRun, review *bool
+	savePlan, script    *string
+	planHash            *bool
+}
+
+func addRenameFlags(fs *flag.FlagSet) renameFlags {
+	return renameFlags{
+		yes:      fs.Bool("yes", false, "Apply the changes without prompting for confirmation"),
+		dryRun:   fs.Bool("dry-run", false, "Print the proposed changes without applying them"),
+		review:   fs.Bool("review", false, "Accept, skip or edit each proposed rename in turn before applying them"),
+		savePlan: fs.String("save-plan", "", "Write the proposed changes to this plan file, to be applied later with the apply command, instead of applying them"),
+		script:   fs.String("script", "", "Write the proposed changes to this POSIX shell script, and a script undoing them alongside, instead of applying them"),
+		planHash: fs.Bool("plan-hash", false, "With -save-plan, also record the checksum of each file, so that the plan can be applied to another copy of the directory"),
+	}
+}
+
+func runValidate(args []string) int {
+	fs := newFlagSet("validate")
+	s := newSettings(fs)
+	s.checkFlags()
+	format := fs.String("format", "text", "Output format for validation errors: 'text', 'json' or 'lines'")
+	quiet := fs.Bool("quiet", false, "Do not print validation errors; only set the exit code")
+	integrity := fs.Bool("integrity", false, "Check that file contents match their extensions and are not empty or truncated")
+	decode := fs.Bool("decode", false, "With -integrity, also fully decode every image")
+	chronology := fs.Bool("chronology", false, "Check that major groups are numbered in the order of their EXIF capture times")
+	workers := fs.Int("workers", runtime.NumCPU(), "Number of files to check concurrently with -integrity or -chronology")
+	staged := fs.Bool("staged", false, "Only report errors involving files staged in git, e.g. in a pre-commit hook")
+	changed := fs.Bool("changed", false, "Only report errors involving files which are staged, modified or untracked in git")
+	s.parse(args)
+	ws := s.resolve()
+
+	failClasses, err := failOnClasses(ws.cfg.Check.FailOn)
+	if err != nil {
+		usageError(err)
+	}
+	extra := make(dirnum.ValidationErrors)
+	if *integrity {
+		integrityErrors, _, err := ws.schema.CheckIntegrity(ws.dir, ws.files, *decode, *workers)
+		if err != nil {
+			fatal(err)
+		}
+		extra.Merge(integrityErrors)
+	}
+	if *chronology {
+		extra.Merge(ws.schema.ValidateChronology(ws.files, dirnum.ReadCaptureTimes(ws.dir, ws.files, *workers)))
+	}
+	var repo *dirnum.GitRepo
+	if *staged || *changed {
+		if repo, err = dirnum.OpenGitRepo(ws.dir); err != nil {
+			usageError(err)
+		}
+	}
+	if *staged {
+		// Validate the names as they will be committed, which may differ from the work tree
+		if ws.files, err = repo.IndexedFiles(ws.dir, ws.cfg.Ignore...); err != nil {
+			fatal(err)
+		}
+	}
+	errors, _ := ws.validate(extra)
+	if repo != nil {
+		changedFiles, err := repo.ChangedFiles(ws.dir, *staged)
+		if err != nil {
+			fatal(err)
+		}
+		errors = errors.Involving(changedFiles)
+	}
+	if !*quiet {
+		printErrors(errors, *format)
+		printSubdirectories(ws, *format)
+	}
+	return checkExitCode(errors, failClasses, ws.cfg.Check.FailSeverity)
+}
+
+func runBaseline(args []string) int {
+	fs := newFlagSet("baseline")
+	s := newSettings(fs)
+	s.baselineFlag()
+	s.parse(args)
+	writeBaseline(s.resolve())
+	return ExitOK
+}
+
+func runRenumber(args []string) int {
+	fs := newFlagSet("renumber")
+	s := newSettings(fs)
+	chronological := fs.Bool("chronological", false, "First sort major groups by the EXIF capture time of their earliest file")
+	workers := fs.Int("workers", runtime.NumCPU(), "Number of files to read concurrently with -chronological")
+	rf := addRenameFlags(fs)
+	s.parse(args)
+	ws := s.resolve()
+
+	var ren []dirnum.RenameEntry
+	if *chronological {
+		ren = ws.schema.ComputeChronologicalOrder(ws.renumberable(ws.files), dirnum.ReadCaptureTimes(ws.dir, ws.files, *workers))
+	}
+	files := dirnum.RenamedNames(ws.files, ren)
+	_, unused := ws.validateNames(files)
+	ren = dirnum.ComposeRenames(ren, ws.schema.ComputeRenames(ws.renumberable(files), unused))
+	proposeRenames(ws, "renumber", ren, rf, "Proposed renames:", "No proposed renames.")
+	return ExitOK
+}
+
+func runFix(args []string) int {
+	fs := newFlagSet("fix")
+	s := newSettings(fs)
+	integrity := fs.Bool("integrity", false, "Also give files whose contents do not match their extension the correct extension")
+	workers := fs.Int("workers", runtime.NumCPU(), "Number of files to check concurrently with -integrity")
+	rf := addRenameFlags(fs)
+	s.parse(args)
+	ws := s.resolve()
+
+	var ren []dirnum.RenameEntry
+	if *integrity {
+		var err error
+		if _, ren, err = ws.schema.CheckIntegrity(ws.dir, ws.files, false, *workers); err != nil {
+			fatal(err)
+		}
+	}
+	ren = dirnum.ComposeRenames(ren, ws.schema.ComputeFixes(dirnum.RenamedNames(ws.files, ren)))
+	proposeRenames(ws, "fix", ren, rf, "Proposed fixes:", "No proposed fixes.")
+	return ExitOK
+}
+
+func runAppend(args []string) int {
+	fs := newFlagSet("append")
+	s := newSettings(fs)
+	from := fs.String("from", "", "The version to move files from, such as 12 or 12-3 (mandatory)")
+	onto := fs.String("onto", "", "The version to append files onto, such as 11 or 12-1 (mandatory)")
+	rf := addRenameFlags(fs)
+	s.parse(args)
+	fromVersion, ontoVersion := versionFlag("from", *from), versionFlag("onto", *onto)
+	ws := s.resolve()
+
+	ren, err := ws.schema.ComputeAppendVersion(ws.files, fromVersion, ontoVersion)
+	if err != nil {
+		usageError(err)
+	}
+	proposeRenames(ws, fmt.Sprintf("append %s onto %s", *from, *onto), ren, rf,
+		fmt.Sprintf("Proposed append from %s onto %s:", *from, *onto), "No proposed renames for append.")
+	return ExitOK
+}
+
+func runSplit(args []string) int {
+	fs := newFlagSet("split")
+	s := newSettings(fs)
+	from := fs.String("from", "", "The version of the group to move, such as 12-3 (mandatory)")
+	to := fs.String("to", "", "The unused version to move it to, such as 40 or 12-7 (mandatory)")
+	rf := addRenameFlags(fs)
+	s.parse(args)
+	fromVersion, toVersion := versionFlag("from", *from), versionFlag("to", *to)
+	ws := s.resolve()
+
+	ren, err := ws.schema.ComputeSplit(ws.files, fromVersion, toVersion)
+	if err != nil {
+		usageError(err)
+	}
+	proposeRenames(ws, fmt.Sprintf("split %s to %s", *from, *to), ren, rf,
+		fmt.Sprintf("Proposed split of %s to %s:", *from, *to), "No proposed renames for split.")
+	return ExitOK
+}
+
+func runMigrate(args []string) int {
+	fs := newFlagSet("migrate")
+	s := newSettings(fs)
+	to := fs.String("to", "", "The descriptor style to rename files to: 'plain', 'dashes' or 'brackets' (mandatory)")
+	rf := addRenameFlags(fs)
+	s.parse(args)
+	if *to == "" {
+		usageError(fmt.Errorf("-to is required"))
+	}
+	style, err := dirnum.ParseDescriptorStyle(*to)
+	if err != nil {
+		usageError(err)
+	}
+	ws := s.resolve()
+	target, err := ws.schema.WithDescriptorStyle(style)
+	if err != nil {
+		usageError(err)
+	}
+
+	ren, errs := ws.schema.ComputeMigration(ws.files, target)
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	from := ws.cfg.Schema.DescriptorStyle
+	if from == "" {
+		from = dirnum.DescriptorPlain
+	}
+	operation := fmt.Sprintf("migrate from %s to %s", from, style)
+	if proposeRenames(ws, operation, ren, rf, fmt.Sprintf("Proposed migration to %s descriptors:", *to), "No proposed renames for migration.") {
+		setMigratedStyle(ws.dir, operation, false)
+	}
+	return ExitOK
+}
+
+// setMigratedStyle sets the descriptor style in the configuration file of dir to match the file names after the
+// operation is applied or undone, if it is a migration between descriptor styles
+func setMigratedStyle(dir, operation string, undone bool) {
+	var from, to string
+	if _, err := fmt.Sscanf(operation, "migrate from %s to %s", &from, &to); err != nil {
+		return
+	}
+	style := to
+	if undone {
+		style = from
+	}
+	if err := dirnum.SetDescriptorStyle(dir, dirnum.DescriptorStyle(style)); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("Set \"descriptorStyle\": %q in %s.\n", style, filepath.Join(dir, dirnum.ConfigFileName))
+}
+
+// versionFlag parses the value of a mandatory version flag
+func versionFlag(name, value string) []int {
+	if value == "" {
+		usageError(fmt.Errorf("-%s is required", name))
+	}
+	v, err := dirnum.ParseVersion(value)
+	if err != nil {
+		usageError(fmt.Errorf("-%s: %w", name, err))
+	}
+	return v
+}
+
+func runApply(args []string) int {
+	fs := newFlagSet("apply")
+	s := newSettings(fs)
+	planPath := fs.String("plan", "", "The plan file to apply, as written by -save-plan (mandatory)")
+	rf := addRenameFlags(fs)
+	s.parse(args)
+	if *planPath == "" {
+		usageError(fmt.Errorf("-plan is required"))
+	}
+	ws := s.resolve()
+
+	plan, err := dirnum.ReadPlan(*planPath)
+	if err != nil {
+		fatal(err)
+	}
+	if err := plan.CheckDrift(ws.dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Refusing to apply %s: %v\n", *planPath, err)
+		return ExitInvalid
+	}
+	if proposeRenames(ws, plan.Operation, plan.Renames, rf,
+		fmt.Sprintf("Plan for %s, made %s:", plan.Operation, plan.Created.Local().Format("2006-01-02 15:04:05")), "The plan has no renames.") {
+		setMigratedStyle(ws.dir, plan.Operation, false)
+	}
+	return ExitOK
+}
+
+func runUndo(args []string) int {
+	fs := newFlagSet("undo")
+	s := newSettings(fs)
+	yes := fs.Bool("yes", false, "Undo without prompting for confirmation")
+	s.parse(args)
+	ws := s.resolve()
+
+	journal := ws.journal()
+	last := journal.Last()
+	if last == nil {
+		fmt.Println("Nothing to undo.")
+		return ExitOK
+	}
+	fmt.Printf("Last operation: %s at %s\n", last.Operation, last.Time.Format("2006-01-02 15:04:05"))
+	if last.Interrupted() {
+		fmt.Println("It was interrupted; the files it renamed will be given their old names again.")
+	}
+	for _, r := range dirnum.Reverse(last.Renames) {
+		fmt.Printf("%s => %s\n", r.OldName, r.NewName)
+	}
+	if !*yes && !prompt("Undo?") {
+		return ExitOK
+	}
+	ws.lock("undo")
+	defer releaseLock()
+	// Another run may have renamed files after the journal was read but before the lock was acquired
+	journal = ws.journal()
+	if current := journal.Last(); current == nil || !current.Time.Equal(last.Time) || current.Operation != last.Operation ||
+		current.Progress != last.Progress || !slices.Equal(current.Renames, last.Renames) {
+		fatal(fmt.Errorf("the journal of %s changed while waiting for confirmation; run dirnum undo again", ws.dir))
+	}
+	entry, err := journal.Undo()
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Printf("Undid %s.\n", entry.Operation)
+	setMigratedStyle(ws.dir, entry.Operation, true)
+	return ExitOK
+}
+
+func runDupes(args []string) int {
+	fs := newFlagSet("dupes")
+	s := newSettings(fs)
+	workers := fs.Int("workers", runtime.NumCPU(), "Number of files to hash concurrently")
+	format := fs.String("format", "text", "Output format for the report: 'text' or 'json'")
+	remove := fs.Bool("remove", false, "Move all but the first file of each group into the trash folder, then offer to renumber")
+	mergeTags := fs.Bool("merge-tags", false, "When removing duplicates, add their tags to the file which is kept")
+	trash := fs.String("trash", dirnum.DefaultTrashDir, "Subdirectory to move duplicates into; its name must begin with .dirnum")
+	rf := addRenameFlags(fs)
+	s.parse(args)
+	if err := dirnum.ValidateTrashDir(*trash); err != nil {
+		usageError(err)
+	}
+	ws := s.resolve()
+
+	groups, err := ws.schema.FindDuplicates(ws.dir, ws.files, *workers)
+	if err != nil {
+		fatal(err)
+	}
+	switch *format {
+	case "json":
+		out, err := json.MarshalIndent(groups, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(string(out))
+	case "text":
+		printDuplicates(groups)
+	default:
+		usageError(fmt.Errorf("unknown output format %q", *format))
+	}
+	if !*remove || len(groups) == 0 {
+		return ExitOK
+	}
+
+	ren, err := ws.schema.PlanDuplicateRemoval(ws.dir, *trash, groups, *mergeTags)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println()
+	if !proposeRenames(ws, "remove duplicates", ren, rf, "Proposed removals:", "No proposed removals.") {
+		return ExitOK
+	}
+
+	// Removing files leaves gaps, so offer to fill them
+	ws.reload()
+	_, unused := ws.validateNames(ws.files)
+	fmt.Println()
+	proposeRenames(ws, "renumber", ws.schema.ComputeRenames(ws.renumberable(ws.files), unused), rf, "Proposed renames:", "No proposed renames.")
+	return ExitOK
+}
+
+// printDuplicates prints each group of identical files with their versions
+func printDuplicates(groups []dirnum.DuplicateGroup) {
+	if len(groups) == 0 {
+		fmt.Println("No duplicates found")
+		return
+	}
+	for i, g := range groups {
+		fmt.Printf("Duplicate group %d (%d bytes, sha256 %s):\n", i+1, g.Size, g.Hash[:12])
+		for _, d := range g.Files {
+			switch {
+			case d.Major == dirnum.NoVersion:
+				fmt.Printf("  %s\n", d.Name)
+			case d.Minor == dirnum.NoVersion:
+				fmt.Printf("  %s (major %d)\n", d.Name, d.Major)
+			default:
+				fmt.Printf("  %s (major %d, minor %d)\n", d.Name, d.Major, d.Minor)
+			}
+		}
+	}
+}
+
+func runSimilar(args []string) int {
+	fs := newFlagSet("similar")
+	s := newSettings(fs)
+	workers := fs.Int("workers", runtime.NumCPU(), "Number of images to decode and compare concurrently")
+	hash := fs.String("hash", string(dirnum.DifferenceHashAlgorithm), "Perceptual hash to compare: 'dhash' (difference) or 'ahash' (average)")
+	threshold := fs.Int("threshold", 8, "Maximum number of differing hash bits (0-64) for images to be reported as similar")
+	recursive := fs.Bool("recursive", false, "Compare every image below the directory rather than only the files within it")
+	format := fs.String("format", "text", "Output format for the report: 'text' or 'json'")
+	s.parse(args)
+	algorithm, err := dirnum.ParseHashAlgorithm(*hash)
+	if err != nil {
+		usageError(err)
+	}
+	ws := s.resolve()
+
+	files := dirnum.ImageFiles(ws.files)
+	if *recursive {
+		if files, err = dirnum.ImageFilesInTree(ws.dir, ws.cfg.Ignore...); err != nil {
+			fatal(err)
+		}
+	}
+	hashes, errs := dirnum.HashImages(ws.dir, files, *workers)
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	pairs := dirnum.FindSimilar(hashes, algorithm, *threshold, *workers)
+
+	switch *format {
+	case "json":
+		out, err := json.MarshalIndent(pairs, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(string(out))
+	case "text":
+		if len(pairs) == 0 {
+			fmt.Println("No similar images found")
+		}
+		for _, p := range pairs {
+			fmt.Printf("%d\t%s\t%s\n", p.Distance, p.A, p.B)
+		}
+	default:
+		usageError(fmt.Errorf("unknown output format %q", *format))
+	}
+	return ExitOK
+}
+
+func runSheet(args []string) int {
+	fs := newFlagSet("sheet")
+	s := newSettings(fs)
+	groups := fs.Bool("groups", false, "Render a separate sheet for each major group rather than one for the whole directory")
+	out := fs.String("out", "", "Directory to write the sheets to (default: "+sheetDir+" within the directory)")
+	size := fs.Int("size", dirnum.DefaultContactSheetOptions.ThumbSize, "Width and height in pixels of each thumbnail")
+	columns := fs.Int("columns", dirnum.DefaultContactSheetOptions.Columns, "Number of thumbnails in each row")
+	rows := fs.Int("rows", dirnum.DefaultContactSheetOptions.Rows, "Most rows on each sheet; longer sheets are split into numbered pages")
+	workers := fs.Int("workers", runtime.NumCPU(), "Number of images to decode and compare concurrently")
+	s.parse(args)
+	if *size <= 0 || *columns <= 0 || *rows <= 0 {
+		usageError(fmt.Errorf("-size, -columns and -rows must be positive"))
+	}
+	ws := s.resolve()
+	if *out == "" {
+		*out = filepath.Join(ws.dir, sheetDir)
+	}
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		fatal(err)
+	}
+
+	// Sheets show files in numbering order, followed by any which do not match the schema
+	parsed := ws.schema.ParseFileNames(dirnum.ImageFiles(ws.files))
+	sheets := make(map[string][]string)
+	var names []string
+	add := func(name, file string) {
+		if _, found := sheets[name]; !found {
+			names = append(names, name)
+		}
+		sheets[name] = append(sheets[name], file)
+	}
+	for _, f := range parsed {
+		if *groups {
+			add(fmt.Sprintf("major-%0*d.png", f.MajorDigits, f.Major), f.OriginalName)
+		} else {
+			add("contact-sheet.png", f.OriginalName)
+		}
+	}
+	if !*groups {
+		numbered := make(map[string]bool)
+		for _, f := range parsed {
+			numbered[f.OriginalName] = true
+		}
+		for _, f := range dirnum.ImageFiles(ws.files) {
+			if !numbered[f] {
+				add("contact-sheet.png", f)
+			}
+		}
+	}
+	if len(names) == 0 {
+		fmt.Println("No images found")
+		return ExitOK
+	}
+
+	// Long sheets are split into pages, the first keeping the sheet's name and the rest numbered from 2
+	opts := dirnum.ContactSheetOptions{ThumbSize: *size, Columns: *columns, Rows: *rows}
+	for _, name := range names {
+		for i, page := range dirnum.ContactSheetPages(sheets[name], opts) {
+			sheet, errs, err := dirnum.RenderContactSheet(ws.dir, page, opts, *workers)
+			if err != nil {
+				usageError(err)
+			}
+			for _, err := range errs {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			path := filepath.Join(*out, name)
+			if i > 0 {
+				path = strings.TrimSuffix(path, ".png") + fmt.Sprintf("-%d.png", i+1)
+			}
+			if err := writePNG(path, sheet); err != nil {
+				fatal(err)
+			}
+			fmt.Printf("Wrote %s (%d files)\n", path, len(page))
+		}
+	}
+	return ExitOK
+}
+
+// sheetDir is the default directory contact sheets are written to.  Like dirnum's other files it is hidden from
+// validation.
+const sheetDir = ".dirnum-sheets"
+
+// writePNG encodes an image as a PNG file
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func runManifest(args []string) int {
+	fs := newFlagSet("manifest")
+	s := newSettings(fs)
+	update := fs.Bool("update", false, "Add new files to the existing manifest and drop missing ones, without rehashing the rest")
+	workers := fs.Int("workers", runtime.NumCPU(), "Number of files to hash concurrently")
+	s.parse(args)
+	ws := s.resolve()
+	ws.lock("manifest")
+	defer releaseLock()
+
+	var m dirnum.Manifest
+	var err error
+	if *update {
+		if m, err = dirnum.ReadManifest(ws.dir); err != nil {
+			fatal(err)
+		}
+		err = m.Update(ws.dir, ws.files, *workers)
+	} else {
+		m, err = dirnum.GenerateManifest(ws.dir, ws.files, *workers)
+	}
+	if err != nil {
+		fatal(err)
+	}
+	if err := dirnum.WriteManifest(ws.dir, m); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("Recorded %d checksums in %s\n", len(m), filepath.Join(ws.dir, dirnum.ManifestFileName))
+	return ExitOK
+}
+
+func runVerify(args []string) int {
+	fs := newFlagSet("verify")
+	s := newSettings(fs)
+	format := fs.String("format", "text", "Output format for errors: 'text', 'json' or 'lines'")
+	workers := fs.Int("workers", runtime.NumCPU(), "Number of files to hash concurrently")
+	s.parse(args)
+	ws := s.resolve()
+
+	m, err := dirnum.ReadManifest(ws.dir)
+	if err != nil {
+		fatal(err)
+	}
+	errors := m.Verify(ws.dir, ws.files, *workers)
+	printErrors(errors, *format)
+	return checkExitCode(errors, map[string]bool{classInvalid: true}, dirnum.SeverityError)
+}
+
+func runExportCommand(args []string) int {
+	fs := newFlagSet("export")
+	s := newSettings(fs)
+	s.exportFlags("")
+	yes := fs.Bool("yes", false, "Export without prompting for confirmation")
+	s.parse(args)
+	if err := runExport(s.resolve(), *yes); err != nil {
+		fatal(err)
+	}
+	return ExitOK
+}
+
+func runStats(args []string) int {
+	fs := newFlagSet("stats")
+	s := newSettings(fs)
+	s.statsFlags("")
+	s.parse(args)
+	printStats(s.resolve())
+	return ExitOK
+}
+
+func runConfig(args []string) int {
+	fs := newFlagSet("config")
+	s := newSettings(fs)
+	s.checkFlags()
+	s.exportFlags("export-")
+	s.statsFlags("stats-")
+	s.parse(args)
+	printConfig(s.resolve())
+	return ExitOK
+}
+
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		printCommands()
+		return ExitOK
+	}
+	c := findCommand(args[0])
+	if c == nil || c.name == "help" {
+		printCommands()
+		return ExitUsage
+	}
+	return c.run([]string{"-help"})
+}
+
+// printErrors prints validation errors in the requested format
+func printErrors(errors dirnum.ValidationErrors, format string) {
+	out, err := errors.Format(format)
+	if err != nil {
+		usageError(err)
+	}
+	fmt.Println(out)
+}
+
+// printSubdirectories lists the subdirectories which were not validated.  Machine-readable formats only report
+// validation errors, so they are listed on stderr.
+func printSubdirectories(ws *workspace, format string) {
+	dirs := ws.otherDirs()
+	if len(dirs) == 0 {
+		return
+	}
+	out := os.Stdout
+	if format != "text" {
+		out = os.Stderr
+	}
+	fmt.Fprintf(out, "Skipped %d subdirectories, which are not numbered: %s\n", len(dirs), strings.Join(dirs, ", "))
+}
+
+// writeBaseline records the current validation errors as the baseline
+func writeBaseline(ws *workspace) {
+	path := ws.baselinePath()
+	if path == "none" {
+		usageError(fmt.Errorf("writing a baseline requires a baseline file"))
+	}
+	errors, _ := ws.validateNames(ws.files)
+	if err := dirnum.WriteBaseline(path, dirnum.NewBaseline(errors)); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("Recorded %d errors in %s\n", len(errors.All()), path)
+}
+
+// printConfig prints the effective configuration as JSON, and the files it was read from to stderr
+func printConfig(ws *workspace) {
+	for _, p := range ws.configPaths {
+		fmt.Fprintf(os.Stderr, "Read %s\n", p)
+	}
+	out, err := json.MarshalIndent(ws.cfg, "", "  ")
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println(string(out))
+}
+
+// proposeRenames prints a rename plan and applies it after confirmation, or after the user reviews it with
+// -review, recording it in the journal so that it can be undone.  It reports whether any files were renamed.
+func proposeRenames(ws *workspace, operation string, ren []dirnum.RenameEntry, rf renameFlags, heading, none string) bool {
+	if len(ren) == 0 {
+		fmt.Println(none)
+		return false
+	}
+	fmt.Println(heading)
+	for _, r := range ren {
+		fmt.Printf("%s => %s\n", r.OldName, r.NewName)
+	}
+	if *rf.savePlan != "" || *rf.script != "" {
+		if *rf.savePlan != "" {
+			savePlan(ws, operation, ren, *rf.savePlan, *rf.planHash)
+		}
+		if *rf.script != "" {
+			writeScripts(ws, operation, ren, *rf.script)
+		}
+		return false
+	}
+	if *rf.dryRun {
+		return false
+	}
+	if *rf.review {
+		if ren = reviewRenames(ws, ren, stdin); ren == nil {
+			return false
+		}
+	} else if !*rf.yes && !prompt("Rename files?") {
+		return false
+	}
+	renameFiles(ws, operation, ren)
+	return true
+}
+
+// savePlan writes a rename plan to a plan file, recording the state of the files it renames
+func savePlan(ws *workspace, operation string, ren []dirnum.RenameEntry, path string, hash bool) {
+	plan, err := dirnum.NewPlan(ws.dir, operation, ren, hash)
+	if err != nil {
+		fatal(err)
+	}
+	if err := dirnum.WritePlan(path, plan); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("Wrote the plan to %s; apply it with: %s apply -plan %s %s\n", path, os.Args[0], path, ws.dir)
+}
+
+// writeScripts writes a rename plan as a shell script, and the script which undoes it alongside with "-undo" added
+// to its name
+func writeScripts(ws *workspace, operation string, ren []dirnum.RenameEntry, path string) {
+	dir, err := filepath.Abs(ws.dir)
+	if err != nil {
+		fatal(err)
+	}
+	ext := filepath.Ext(path)
+	undoPath := strings.TrimSuffix(path, ext) + "-undo" + ext
+	header := "Generated by dirnum: " + operation
+	scripts := map[string]string{
+		path:     dirnum.ShellScript(dir, header, ren),
+		undoPath: dirnum.UndoShellScript(dir, header+" (undo)", ren),
+	}
+	for p, script := range scripts {
+		if err := os.WriteFile(p, []byte(script), 0755); err != nil {
+			fatal(err)
+		}
+	}
+	fmt.Printf("Wrote the script %s and the script undoing it %s\n", path, undoPath)
+}
+
+// printStats prints tag statistics according to the configuration
+func printStats(ws *workspace) {
+	computedStats := ws.schema.ComputeStats(ws.files)
+	if ws.cfg.Stats.Sort == "freq" {
+		dirnum.SortStatsByFrequency(computedStats)
+	} else {
+		dirnum.SortStatsAlphabetical(computedStats)
+	}
+
+	if ws.cfg.Stats.Names {
+		printTagMajorVersions(computedStats)
+	} else {
+		printTagCounts(computedStats)
+	}
+}
+
+// Lists the names of the commands, for error messages
+func commandNames() string {
+	names := make([]string, len(commands))
+	for i, c := range commands {
+		names[i] = c.name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,123 @@
+package dirnum
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// BaselineFileName is the default name of the baseline file within a directory
+const BaselineFileName = ".dirnum-baseline.json"
+
+// BaselineEntry records the number of accepted errors with a given code for a single file
+type BaselineEntry struct {
+	File  string    `json:"file"`
+	Code  ErrorCode `json:"code"`
+	Count int       `json:"count"`
+}
+
+// Baseline is a set of known, accepted validation errors.  Errors in the baseline are suppressed so that new
+// problems stand out.
+type Baseline struct {
+	Entries []BaselineEntry `json:"entries"`
+}
+
+type baselineKey struct {
+	file string
+	code ErrorCode
+}
+
+// NewBaseline records every error in errors as accepted
+func NewBaseline(errors ValidationErrors) Baseline {
+	counts := make(map[baselineKey]int)
+	for _, e := range errors.All() {
+		if e.Code == CodeStaleBaseline {
+			continue
+		}
+		counts[baselineKey{e.File, e.Code}]++
+	}
+
+	b := Baseline{Entries: make([]BaselineEntry, 0, len(counts))}
+	for k, n := range counts {
+		b.Entries = append(b.Entries, BaselineEntry{File: k.file, Code: k.code, Count: n})
+	}
+	sort.Slice(b.Entries, func(i, j int) bool {
+		if b.Entries[i].File == b.Entries[j].File {
+			return b.Entries[i].Code < b.Entries[j].Code
+		}
+		return b.Entries[i].File < b.Entries[j].File
+	})
+	return b
+}
+
+// ReadBaseline loads a baseline previously written by WriteBaseline
+func ReadBaseline(path string) (Baseline, error) {
+	var b Baseline
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return b, err
+	}
+	if err := json.Unmarshal(data, &b); err != nil {
+		return b, fmt.Errorf("invalid baseline file %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// WriteBaseline saves the baseline as JSON
+func WriteBaseline(path string, b Baseline) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Filter returns the errors which are not accepted by the baseline.  If a file has more errors with a given code
+// than the baseline records, the excess errors are reported.  Baseline entries which record more errors than remain,
+// including those which no longer match any error, are reported as CodeStaleBaseline so that the baseline can be
+// regenerated rather than hiding errors introduced later.
+func (b Baseline) Filter(errors ValidationErrors) ValidationErrors {
+	recorded := make(map[baselineKey]int)
+	for _, entry := range b.Entries {
+		recorded[baselineKey{entry.File, entry.Code}] += entry.Count
+	}
+	remaining := make(map[baselineKey]int, len(recorded))
+	for k, n := range recorded {
+		remaining[k] = n
+	}
+
+	filtered := make(ValidationErrors)
+	for _, e := range errors.All() {
+		k := baselineKey{e.File, e.Code}
+		if remaining[k] > 0 {
+			remaining[k]--
+			continue
+		}
+		filtered.add(e)
+	}
+
+	for _, entry := range b.Entries {
+		k := baselineKey{entry.File, entry.Code}
+		unused := remaining[k]
+		if unused == 0 {
+			continue
+		}
+		// Report entries repeated for the same file and code once
+		delete(remaining, k)
+		message := fmt.Sprintf("Baseline entry for %s no longer applies: %s", entry.Code, entry.File)
+		if unused < recorded[k] {
+			message = fmt.Sprintf("Baseline entry for %s records %d errors but %d remain: %s",
+				entry.Code, recorded[k], recorded[k]-unused, entry.File)
+		}
+		filtered.add(ValidationError{
+			Code:     CodeStaleBaseline,
+			Severity: SeverityWarning,
+			File:     entry.File,
+			Major:    NoVersion,
+			Minor:    NoVersion,
+			Message:  message,
+		})
+	}
+	return filtered
+}
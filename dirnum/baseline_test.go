@@ -0,0 +1,51 @@
+package dirnum
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaselineFilter(t *testing.T) {
+	legacy, _ := ValidateFileNames([]string{"0.jpg", "1-foo.jpg", "foo.jpg", "3.jpg"}, false, false)
+	b := NewBaseline(legacy)
+	assert.Equal(t, []BaselineEntry{
+		{File: "3.jpg", Code: CodeMajorGap, Count: 1},
+		{File: "foo.jpg", Code: CodeBadFilename, Count: 1},
+	}, b.Entries)
+
+	// The known errors are suppressed
+	assert.Empty(t, b.Filter(legacy))
+
+	// A new error is reported, and the fixed major gap is flagged as stale
+	current, _ := ValidateFileNames([]string{"0.jpg", "1-foo.jpg", "foo.jpg", "2.jpg", "bar.gif"}, false, false)
+	filtered := b.Filter(current)
+	codes := make(map[string]ErrorCode)
+	for _, e := range filtered.All() {
+		codes[e.File] = e.Code
+	}
+	assert.Equal(t, map[string]ErrorCode{"bar.gif": CodeBadFilename, "3.jpg": CodeStaleBaseline}, codes)
+}
+
+func TestBaselineRoundTrip(t *testing.T) {
+	errors, _ := ValidateFileNames([]string{"foo.jpg", "1-1.jpg"}, true, false)
+	b := NewBaseline(errors)
+	path := filepath.Join(t.TempDir(), BaselineFileName)
+
+	assert.NoError(t, WriteBaseline(path, b))
+	read, err := ReadBaseline(path)
+	assert.NoError(t, err)
+	assert.Equal(t, b, read)
+}
+
+func TestBaselinePartlyUsed(t *testing.T) {
+	b := Baseline{Entries: []BaselineEntry{{File: "1.jpg", Code: CodeDuplicateMinor, Count: 3}}}
+	errors := make(ValidationErrors)
+	errors.add(ValidationError{Code: CodeDuplicateMinor, Severity: SeverityError, File: "1.jpg", Message: "duplicate"})
+
+	filtered := b.Filter(errors).All()
+	assert.Len(t, filtered, 1)
+	assert.Equal(t, CodeStaleBaseline, filtered[0].Code)
+	assert.Equal(t, "Baseline entry for duplicate-minor records 3 errors but 1 remain: 1.jpg", filtered[0].Message)
+}
@@ -0,0 +1,144 @@
+package dirnum
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// CodeChronology is reported by ValidateChronology
+const CodeChronology ErrorCode = "chronology" // A major group was captured out of order with its neighbours
+
+// chronologyGroup is a major group and the time its earliest file was captured
+type chronologyGroup struct {
+	major int
+	files []*FileNamePieces
+	time  time.Time
+}
+
+// timedGroups returns the major groups, in major order, which have at least one file with a capture time and are
+// not frozen
+func (s *Schema) timedGroups(fileNames []string, times map[string]time.Time) []*chronologyGroup {
+	var groups []*chronologyGroup
+	var current *chronologyGroup
+	for _, f := range s.ParseFileNames(fileNames) {
+		if current == nil || current.major != f.Major {
+			current = &chronologyGroup{major: f.Major}
+			groups = append(groups, current)
+		}
+		current.files = append(current.files, f)
+		if t, found := times[f.OriginalName]; found && (current.time.IsZero() || t.Before(current.time)) {
+			current.time = t
+		}
+	}
+
+	timed := groups[:0]
+	for _, g := range groups {
+		if !g.time.IsZero() && !s.IsFrozen(g.major) {
+			timed = append(timed, g)
+		}
+	}
+	return timed
+}
+
+// ValidateChronology checks capture order using DefaultSchema
+func ValidateChronology(fileNames []string, times map[string]time.Time) ValidationErrors {
+	return DefaultSchema.ValidateChronology(fileNames, times)
+}
+
+// ValidateChronology checks that major groups are numbered in the order they were captured, as read by
+// ReadCaptureTimes.  A group's capture time is that of its earliest file, and groups without one or which are frozen
+// are skipped.  The fewest groups which need to move for the rest to be in order are reported, rather than every
+// group next to one.
+func (s *Schema) ValidateChronology(fileNames []string, times map[string]time.Time) ValidationErrors {
+	groups := s.timedGroups(fileNames, times)
+	inOrder := longestChronologicalRun(groups)
+
+	errors := make(ValidationErrors)
+	for i, g := range groups {
+		if inOrder[i] {
+			continue
+		}
+		f := g.files[0]
+		e := ValidationError{
+			Code:     CodeChronology,
+			Severity: SeverityWarning,
+			File:     f.OriginalName,
+			Major:    f.Major,
+			Minor:    f.Minor,
+		}
+		if i > 0 && groups[i-1].time.After(g.time) {
+			e.Related = []string{groups[i-1].files[0].OriginalName}
+			e.Message = fmt.Sprintf("Major %d was taken %s, before major %d (%s): %s", g.major, formatCaptureTime(g.time),
+				groups[i-1].major, formatCaptureTime(groups[i-1].time), f.OriginalName)
+		} else if i < len(groups)-1 && groups[i+1].time.Before(g.time) {
+			e.Related = []string{groups[i+1].files[0].OriginalName}
+			e.Message = fmt.Sprintf("Major %d was taken %s, after major %d (%s): %s", g.major, formatCaptureTime(g.time),
+				groups[i+1].major, formatCaptureTime(groups[i+1].time), f.OriginalName)
+		} else {
+			e.Message = fmt.Sprintf("Major %d was taken %s, out of order with the groups around it: %s", g.major,
+				formatCaptureTime(g.time), f.OriginalName)
+		}
+		errors.add(e)
+	}
+	return errors
+}
+
+func formatCaptureTime(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// longestChronologicalRun marks the groups belonging to the longest subsequence whose capture times never decrease.
+// Every other group is out of order.
+func longestChronologicalRun(groups []*chronologyGroup) []bool {
+	length := make([]int, len(groups))
+	previous := make([]int, len(groups))
+	best := -1
+	for i, g := range groups {
+		length[i], previous[i] = 1, -1
+		for j := 0; j < i; j++ {
+			if !groups[j].time.After(g.time) && length[j]+1 > length[i] {
+				length[i], previous[i] = length[j]+1, j
+			}
+		}
+		if best < 0 || length[i] > length[best] {
+			best = i
+		}
+	}
+
+	inOrder := make([]bool, len(groups))
+	for i := best; i >= 0; i = previous[i] {
+		inOrder[i] = true
+	}
+	return inOrder
+}
+
+// ComputeChronologicalOrder determines the reorder using DefaultSchema
+func ComputeChronologicalOrder(fileNames []string, times map[string]time.Time) []RenameEntry {
+	return DefaultSchema.ComputeChronologicalOrder(fileNames, times)
+}
+
+// ComputeChronologicalOrder determines the renames which sort major groups by capture time.  The groups with a
+// capture time swap major numbers among themselves, so frozen groups, groups without one and gaps in the numbering
+// stay where they are.  Groups captured at the same time keep their relative order.
+func (s *Schema) ComputeChronologicalOrder(fileNames []string, times map[string]time.Time) []RenameEntry {
+	groups := s.timedGroups(fileNames, times)
+	majors := make([]int, len(groups))
+	digits := make([]int, len(groups))
+	for i, g := range groups {
+		majors[i], digits[i] = g.major, g.files[0].MajorDigits
+	}
+	sorted := append([]*chronologyGroup(nil), groups...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].time.Before(sorted[j].time) })
+
+	var files PFnpSlice
+	for i, g := range sorted {
+		for _, f := range g.files {
+			if f.Major != majors[i] {
+				f.Major, f.MajorDigits = majors[i], digits[i]
+			}
+			files = append(files, f)
+		}
+	}
+	return changedNames(files)
+}
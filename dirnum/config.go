@@ -0,0 +1,156 @@
+package dirnum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// ConfigFileName is the name of the per-directory configuration file
+const ConfigFileName = ".dirnum"
+
+// Config holds the settings which may be stored in a configuration file.  Every field is optional in the file;
+// missing fields keep their defaults.
+type Config struct {
+	IgnoreMajor     bool         `json:"ignoreMajor"`     // Do not report skips in the major version numbering
+	IgnoreMinorZero bool         `json:"ignoreMinorZero"` // Do not report minor numbering which skips zero
+	Ignore          []string     `json:"ignore"`          // Patterns, as in .dirnumignore, of file names which are never validated or renamed
+	GroupDirs       bool         `json:"groupDirs"`       // Validate subdirectories named by a major number as that major group
+	Git             bool         `json:"git"`             // Rename files with git, which must track the directory
+	Schema          SchemaConfig `json:"schema"`
+	Export          ExportConfig `json:"export"`
+	Stats           StatsConfig  `json:"stats"`
+	Check           CheckConfig  `json:"check"`
+}
+
+// ExportConfig holds the settings for exporting files into tag subdirectories
+type ExportConfig struct {
+	Prefix   string `json:"prefix"`   // Only export tags starting with this prefix
+	MinCount int    `json:"minCount"` // Only export tags which appear at least this many times
+}
+
+// StatsConfig holds the settings for tag statistics
+type StatsConfig struct {
+	Sort  string `json:"sort"`  // "alpha" or "freq"
+	Names bool   `json:"names"` // List the major versions where each tag appears instead of counting them
+}
+
+// CheckConfig holds the settings for check mode
+type CheckConfig struct {
+	FailOn       []string `json:"failOn"`       // The classes of error which fail the check: "invalid" and/or "fixable"
+	FailSeverity Severity `json:"failSeverity"` // The minimum severity of error which fails the check
+	Baseline     string   `json:"baseline"`     // The baseline file: a path, "auto" or "none"
+}
+
+// DefaultConfig returns the settings used when no configuration file is present
+func DefaultConfig() Config {
+	// Copy the default schema so that loading a configuration file cannot modify it
+	schema := DefaultSchemaConfig
+	schema.Extensions = slices.Clone(schema.Extensions)
+	schema.ExtensionAliases = maps.Clone(schema.ExtensionAliases)
+	return Config{
+		IgnoreMajor:     true,
+		IgnoreMinorZero: true,
+		Ignore:          []string{},
+		Schema:          schema,
+		Stats:           StatsConfig{Sort: "alpha"},
+		Check: CheckConfig{
+			FailOn:       []string{"invalid", "fixable"},
+			FailSeverity: SeverityWarning,
+			Baseline:     "auto",
+		},
+	}
+}
+
+// LoadConfig finds the configuration files in dir and its parents and merges them over the defaults.  Files closer
+// to dir take precedence over those further up.  It returns the merged configuration and the paths of the files
+// which were read, outermost first.
+func LoadConfig(dir string) (Config, []string, error) {
+	c := DefaultConfig()
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return c, nil, err
+	}
+
+	var paths []string
+	for d := abs; ; d = filepath.Dir(d) {
+		path := filepath.Join(d, ConfigFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			paths = append([]string{path}, paths...)
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return c, paths, err
+		}
+		if err := json.Unmarshal(data, &c); err != nil {
+			return c, paths, fmt.Errorf("invalid configuration file %s: %w", path, err)
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return c, paths, err
+	}
+	return c, paths, nil
+}
+
+// SetDescriptorStyle records a descriptor style in the configuration file of dir, creating the file if necessary.
+// The file's other settings are kept.
+func SetDescriptorStyle(dir string, style DescriptorStyle) error {
+	path := filepath.Join(dir, ConfigFileName)
+	var settings, schema map[string]json.RawMessage
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("invalid configuration file %s: %w", path, err)
+		}
+		if raw, found := settings["schema"]; found {
+			if err := json.Unmarshal(raw, &schema); err != nil {
+				return fmt.Errorf("invalid configuration file %s: %w", path, err)
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if settings == nil {
+		settings = make(map[string]json.RawMessage)
+	}
+	if schema == nil {
+		schema = make(map[string]json.RawMessage)
+	}
+
+	if schema["descriptorStyle"], err = json.Marshal(style); err != nil {
+		return err
+	}
+	if settings["schema"], err = json.Marshal(schema); err != nil {
+		return err
+	}
+	if data, err = json.MarshalIndent(settings, "", "  "); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Validate reports any invalid settings
+func (c Config) Validate() error {
+	for _, p := range c.Ignore {
+		if err := ValidateIgnorePattern(p); err != nil {
+			return err
+		}
+	}
+	if _, err := NewSchema(c.Schema); err != nil {
+		return err
+	}
+	if _, err := ParseSeverity(string(c.Check.FailSeverity)); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package dirnum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigMergesParents(t *testing.T) {
+	root := t.TempDir()
+	child := filepath.Join(root, "child")
+	assert.NoError(t, os.Mkdir(child, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, ConfigFileName),
+		[]byte(`{"ignoreMajor": false, "export": {"prefix": "person"}, "schema": {"extensions": ["jpg", "png"]}}`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(child, ConfigFileName),
+		[]byte(`{"stats": {"sort": "freq"}, "export": {"prefix": "place"}}`), 0644))
+
+	c, paths, err := LoadConfig(child)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, ConfigFileName), filepath.Join(child, ConfigFileName)}, paths)
+	assert.False(t, c.IgnoreMajor)
+	assert.True(t, c.IgnoreMinorZero)
+	assert.Equal(t, "place", c.Export.Prefix)
+	assert.Equal(t, "freq", c.Stats.Sort)
+	assert.Equal(t, []string{"jpg", "png"}, c.Schema.Extensions)
+	assert.Equal(t, DefaultSchemaConfig.Template, c.Schema.Template)
+
+	// The defaults are not modified by loading a file
+	assert.Equal(t, []string{"jpg", "gif"}, DefaultSchemaConfig.Extensions)
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(`{"ignore": ["[a-"]}`), 0644))
+	_, _, err := LoadConfig(dir)
+	assert.Error(t, err)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(`{"schema": {"template": "{major}"}}`), 0644))
+	_, _, err = LoadConfig(dir)
+	assert.Error(t, err)
+}
+
+func TestReadFileNamesIgnore(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"0.jpg", "notes.txt", "Thumbs.db", ConfigFileName} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, f), nil, 0644))
+	}
+	names, err := ReadFileNames(dir, "*.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0.jpg"}, names)
+}
+
+func TestSetDescriptorStyle(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, SetDescriptorStyle(dir, DescriptorDashes))
+	c, _, err := LoadConfig(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, DescriptorDashes, c.Schema.DescriptorStyle)
+
+	// Other settings are kept
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ConfigFileName),
+		[]byte(`{"ignoreMajor": false, "schema": {"extensions": ["jpg"], "descriptorStyle": "dashes"}}`), 0644))
+	assert.NoError(t, SetDescriptorStyle(dir, DescriptorBrackets))
+	c, _, err = LoadConfig(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, DescriptorBrackets, c.Schema.DescriptorStyle)
+	assert.Equal(t, []string{"jpg"}, c.Schema.Extensions)
+	assert.False(t, c.IgnoreMajor)
+}
@@ -0,0 +1,157 @@
+package dirnum
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ContactSheetOptions controls the layout of a contact sheet
+type ContactSheetOptions struct {
+	ThumbSize int // The width and height of the square each thumbnail is fitted within
+	Columns   int // The number of thumbnails in each row
+	Rows      int // The most rows on one sheet; ContactSheetPages splits longer lists of files across several sheets
+}
+
+// DefaultContactSheetOptions lays out thumbnails of 160 pixels in rows of six, with up to 50 rows on each sheet
+var DefaultContactSheetOptions = ContactSheetOptions{ThumbSize: 160, Columns: 6, Rows: 50}
+
+// Contact sheet spacing and colors
+const sheetPadding = 8
+
+// maxSheetPixels bounds the size of a single sheet, whatever the options, to 256 MB of pixels
+const maxSheetPixels = 64 << 20
+
+var (
+	sheetBackground  = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	sheetPlaceholder = color.RGBA{0xDD, 0xDD, 0xDD, 0xFF}
+	sheetText        = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+)
+
+// cell returns the width and height of the space given to each thumbnail and its caption
+func (o ContactSheetOptions) cell() (width, height int) {
+	size := max(o.ThumbSize, glyphAdvance)
+	return size + sheetPadding, size + sheetPadding + glyphHeight + sheetPadding
+}
+
+// PerSheet returns the most files drawn on one sheet: Rows full rows, or fewer if the sheet would be too large
+func (o ContactSheetOptions) PerSheet() int {
+	columns := max(o.Columns, 1)
+	width, height := o.cell()
+	rows := max(maxSheetPixels/((columns*width+sheetPadding)*height), 1)
+	if o.Rows > 0 {
+		rows = min(rows, o.Rows)
+	}
+	return rows * columns
+}
+
+// ContactSheetPages splits files into the lists drawn on each sheet, so that however many files there are, each
+// sheet stays small enough to render
+func ContactSheetPages(files []string, opts ContactSheetOptions) [][]string {
+	var pages [][]string
+	for perSheet := opts.PerSheet(); len(files) > perSheet; files = files[perSheet:] {
+		pages = append(pages, files[:perSheet])
+	}
+	return append(pages, files)
+}
+
+// RenderContactSheet draws a grid of thumbnails of files within a directory, in the order given, with each file's
+// name beneath its thumbnail.  Images are decoded using a pool of workers.  Files which cannot be decoded are drawn
+// as a gray placeholder, so that the sheet still shows every file, and the errors describing them are returned
+// alongside the sheet.  It fails if the files do not fit on one sheet; ContactSheetPages splits them into lists
+// which do.
+func RenderContactSheet(dir string, files []string, opts ContactSheetOptions, workers int) (*image.RGBA, []error, error) {
+	if perSheet := opts.PerSheet(); len(files) > perSheet {
+		return nil, nil, fmt.Errorf("cannot draw %d files on one contact sheet: at most %d fit", len(files), perSheet)
+	}
+	size, columns := max(opts.ThumbSize, glyphAdvance), max(min(opts.Columns, len(files)), 1)
+	rows := (len(files) + columns - 1) / columns
+	cellWidth, cellHeight := opts.cell()
+	bounds := image.Rect(0, 0, columns*cellWidth+sheetPadding, max(rows, 1)*cellHeight+sheetPadding)
+	if bounds.Dx()*bounds.Dy() > maxSheetPixels {
+		return nil, nil, fmt.Errorf("cannot draw a contact sheet of %dx%d pixels: use smaller or fewer thumbnails", bounds.Dx(), bounds.Dy())
+	}
+	sheet := image.NewRGBA(bounds)
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(sheetBackground), image.Point{}, draw.Src)
+
+	type cell struct {
+		index int
+		file  string
+	}
+	cells := make([]cell, len(files))
+	for i, f := range files {
+		cells[i] = cell{i, f}
+	}
+
+	var mu sync.Mutex
+	var errs []error
+	forEach(cells, workers, func(c cell) {
+		origin := image.Pt(sheetPadding+(c.index%columns)*cellWidth, sheetPadding+(c.index/columns)*cellHeight)
+		thumb, err := ThumbnailFile(filepath.Join(dir, c.file), size)
+		// Each cell is a separate region of the sheet, so only the error list needs the lock
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+			draw.Draw(sheet, image.Rectangle{origin, origin.Add(image.Pt(size, size))}, image.NewUniform(sheetPlaceholder), image.Point{}, draw.Src)
+		} else {
+			// Center the thumbnail within its square
+			offset := image.Pt((size-thumb.Bounds().Dx())/2, (size-thumb.Bounds().Dy())/2)
+			draw.Draw(sheet, thumb.Bounds().Add(origin.Add(offset)), thumb, image.Point{}, draw.Over)
+		}
+		drawText(sheet, origin.Add(image.Pt(0, size+sheetPadding/2)), fitText(c.file, size), sheetText)
+	})
+	return sheet, errs, nil
+}
+
+// ThumbnailFile decodes an image file and scales it to fit within a square of the given size
+func ThumbnailFile(path string, size int) (*image.RGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode %s: %w", path, err)
+	}
+	if img.Bounds().Empty() {
+		return nil, fmt.Errorf("cannot draw %s: image is empty", path)
+	}
+	return thumbnail(img, size), nil
+}
+
+// thumbnail scales an image to fit within a square of the given size, preserving its aspect ratio.  Each pixel of
+// the thumbnail is the average of the source pixels it covers.  Images smaller than the square are not enlarged.
+func thumbnail(img image.Image, size int) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(h*size/b.Dx(), 1)
+		} else {
+			w, h = max(w*size/b.Dy(), 1), size
+		}
+	}
+
+	thumb := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+max((y+1)*b.Dy()/h, y*b.Dy()/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+max((x+1)*b.Dx()/w, x*b.Dx()/w+1)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+			thumb.SetRGBA64(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return thumb
+}
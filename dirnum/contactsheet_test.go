@@ -0,0 +1,87 @@
+package dirnum
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderContactSheet(t *testing.T) {
+	dir := t.TempDir()
+	saveImage(t, filepath.Join(dir, "0.png"), testImage(320, 160, false))
+	saveImage(t, filepath.Join(dir, "1-tag.jpg"), testImage(40, 80, true))
+	writeFiles(t, dir, map[string]string{"2.jpg": "not an image"})
+
+	opts := ContactSheetOptions{ThumbSize: 64, Columns: 2}
+	sheet, errs, err := RenderContactSheet(dir, []string{"0.png", "1-tag.jpg", "2.jpg"}, opts, 2)
+	assert.NoError(t, err)
+	assert.Len(t, errs, 1)
+
+	// Two rows of two cells, each a thumbnail and a caption
+	cellWidth, cellHeight := 64+sheetPadding, 64+2*sheetPadding+glyphHeight
+	assert.Equal(t, image.Rect(0, 0, 2*cellWidth+sheetPadding, 2*cellHeight+sheetPadding), sheet.Bounds())
+
+	// The wide image is scaled to 64x32 and centered vertically
+	assert.Equal(t, sheetBackground, sheet.RGBAAt(sheetPadding+32, sheetPadding+8))
+	assert.NotEqual(t, sheetBackground, sheet.RGBAAt(sheetPadding+32, sheetPadding+32))
+
+	// The undecodable file is drawn as a placeholder
+	assert.Equal(t, sheetPlaceholder, sheet.RGBAAt(sheetPadding+32, cellHeight+sheetPadding+32))
+
+	// Every file is captioned
+	for i := 0; i < 3; i++ {
+		x, y := sheetPadding+(i%2)*cellWidth, sheetPadding+(i/2)*cellHeight+64+sheetPadding/2
+		caption := sheet.SubImage(image.Rect(x, y, x+64, y+glyphHeight)).(*image.RGBA)
+		assert.True(t, containsColor(caption, sheetText), "caption %d", i)
+	}
+}
+
+func TestContactSheetPages(t *testing.T) {
+	files := make([]string, 25)
+	for i := range files {
+		files[i] = strconv.Itoa(i) + ".jpg"
+	}
+	opts := ContactSheetOptions{ThumbSize: 64, Columns: 4, Rows: 3}
+	pages := ContactSheetPages(files, opts)
+	assert.Equal(t, [][]string{files[:12], files[12:24], files[24:]}, pages)
+	assert.Equal(t, [][]string{files}, ContactSheetPages(files, ContactSheetOptions{ThumbSize: 64, Columns: 5, Rows: 5}))
+
+	// Too many files for one sheet are refused rather than drawn
+	_, _, err := RenderContactSheet(t.TempDir(), files, opts, 1)
+	assert.Error(t, err)
+
+	// However many rows are asked for, a sheet stays within the memory limit
+	huge := ContactSheetOptions{ThumbSize: 1000, Columns: 10, Rows: 1000}
+	width, height := huge.cell()
+	assert.LessOrEqual(t, huge.PerSheet()/10*height*(10*width+sheetPadding), maxSheetPixels)
+	_, _, err = RenderContactSheet(t.TempDir(), files[:1], ContactSheetOptions{ThumbSize: 10000, Columns: 1}, 1)
+	assert.Error(t, err)
+}
+
+func containsColor(img *image.RGBA, c color.RGBA) bool {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.RGBAAt(x, y) == c {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestThumbnail(t *testing.T) {
+	assert.Equal(t, image.Rect(0, 0, 100, 50), thumbnail(testImage(400, 200, false), 100).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 25, 100), thumbnail(testImage(100, 400, false), 100).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 30, 20), thumbnail(testImage(30, 20, false), 100).Bounds())
+}
+
+func TestFitText(t *testing.T) {
+	assert.Equal(t, "0012-tag.jpg", fitText("0012-tag.jpg", 100))
+	assert.Equal(t, "0012-t..", fitText("0012-tag.jpg", 8*glyphAdvance))
+	assert.Equal(t, "", fitText("0012-tag.jpg", 0))
+}
@@ -1,4 +1,4 @@
-package main
+package dirnum
 
 import (
 	"testing"
@@ -36,13 +36,72 @@ func TestInvalidFiles(t *testing.T) {
 	}
 }
 
+func TestParseFileName(t *testing.T) {
+	f, err := ParseFileName("0012-03-Foo, Bar.jpeg")
+	assert.NoError(t, err)
+	assert.Equal(t, &FileNamePieces{
+		Major:        12,
+		Minor:        3,
+		MajorDigits:  2,
+		MinorDigits:  1,
+		OriginalName: "0012-03-Foo, Bar.jpeg",
+		Descriptor:   "Foo, Bar",
+		Extension:    "jpg",
+	}, f)
+	assert.Equal(t, []string{"Foo", "Bar"}, f.Tags())
+
+	f.MajorDigits, f.MinorDigits = 4, 2
+	assert.Equal(t, "0012-03-Foo, Bar.jpg", f.String())
+}
+
+func TestErrorCodes(t *testing.T) {
+	files := []string{"foo.jpg", "1.jpg", "1-bar.jpg", "2-0.jpg", "2-0-baz.jpg", "2-1.jpg", "3-1.jpg", "4-0.jpg", "4-2.jpg"}
+	errors, _ := ValidateFileNames(files, true, true)
+
+	codes := make(map[string][]ErrorCode)
+	for _, e := range errors.All() {
+		codes[e.File] = append(codes[e.File], e.Code)
+	}
+	assert.Equal(t, map[string][]ErrorCode{
+		"foo.jpg":     {CodeBadFilename},
+		"1.jpg":       {CodeOverriddenMajor},
+		"1-bar.jpg":   {CodeOverriddenMajor},
+		"2-0.jpg":     {CodeDuplicateMinor},
+		"2-0-baz.jpg": {CodeDuplicateMinor},
+		"3-1.jpg":     {CodeMinorOnSingle},
+		"4-2.jpg":     {CodeMinorGap},
+	}, codes)
+	assert.Equal(t, []string{"1.jpg"}, errors["1-bar.jpg"][0].Related)
+}
+
+func TestErrorFormats(t *testing.T) {
+	errors, _ := ValidateFileNames([]string{"foo.jpg", "0.jpg", "2.jpg"}, false, false)
+
+	lines, err := errors.Format("lines")
+	assert.NoError(t, err)
+	assert.Equal(t, "2.jpg\twarning\tmajor-gap\tNumbering jumped from 0 to 2: 2.jpg\n"+
+		"foo.jpg\terror\tbad-filename\tbad filename: foo.jpg\n", lines)
+
+	js, err := errors.Format("json")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"code": "major-gap", "severity": "warning", "file": "2.jpg", "major": 2, "minor": null,
+		 "message": "Numbering jumped from 0 to 2: 2.jpg"},
+		{"code": "bad-filename", "severity": "error", "file": "foo.jpg", "major": null, "minor": null,
+		 "message": "bad filename: foo.jpg"}
+	]`, js)
+
+	_, err = errors.Format("xml")
+	assert.Error(t, err)
+}
+
 func TestRenameFillGaps(t *testing.T) {
 	files := []string{"1.jpg", "2-Foo.jpg", "5-0-Foo.jpg", "5-1.jpg", "5-2.jpg", "6.jpg"}
 	expected := []RenameEntry{
-		{oldName: "5-0-Foo.jpg", newName: "0-0-Foo.jpg"},
-		{oldName: "5-1.jpg", newName: "0-1.jpg"},
-		{oldName: "5-2.jpg", newName: "0-2.jpg"},
-		{oldName: "6.jpg", newName: "3.jpg"},
+		{OldName: "5-0-Foo.jpg", NewName: "0-0-Foo.jpg"},
+		{OldName: "5-1.jpg", NewName: "0-1.jpg"},
+		{OldName: "5-2.jpg", NewName: "0-2.jpg"},
+		{OldName: "6.jpg", NewName: "3.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeRenames(files, []int{0, 3, 4}))
 }
@@ -50,10 +109,17 @@ func TestRenameFillGaps(t *testing.T) {
 func TestMissingZero(t *testing.T) {
 	files := []string{"0.jpg", "0-1.jpg", "0-2-Foo.jpg"}
 	expectedRenames := []RenameEntry{
-		{oldName: "0.jpg", newName: "0-0.jpg"},
+		{OldName: "0.jpg", NewName: "0-0.jpg"},
 	}
 	expectedErrors := ValidationErrors{
-		"0.jpg": []string{"Minor version numbering must start with 0: 0.jpg"},
+		"0.jpg": {{
+			Code:     CodeMinorStart,
+			Severity: SeverityWarning,
+			File:     "0.jpg",
+			Major:    0,
+			Minor:    NoVersion,
+			Message:  "Minor version numbering must start with 0: 0.jpg",
+		}},
 	}
 
 	errors, unused := ValidateFileNames(files, true, false)
@@ -64,10 +130,17 @@ func TestMissingZero(t *testing.T) {
 func TestMissingZeroWithDescriptor(t *testing.T) {
 	files := []string{"0-Bar Baz.jpg", "0-1.jpg"}
 	expectedRenames := []RenameEntry{
-		{oldName: "0-Bar Baz.jpg", newName: "0-0-Bar Baz.jpg"},
+		{OldName: "0-Bar Baz.jpg", NewName: "0-0-Bar Baz.jpg"},
 	}
 	expectedErrors := ValidationErrors{
-		"0-Bar Baz.jpg": []string{"Minor version numbering must start with 0: 0-Bar Baz.jpg"},
+		"0-Bar Baz.jpg": {{
+			Code:     CodeMinorStart,
+			Severity: SeverityWarning,
+			File:     "0-Bar Baz.jpg",
+			Major:    0,
+			Minor:    NoVersion,
+			Message:  "Minor version numbering must start with 0: 0-Bar Baz.jpg",
+		}},
 	}
 
 	errors, unused := ValidateFileNames(files, true, false)
@@ -82,7 +155,7 @@ func TestRenameFillGapsEmpty(t *testing.T) {
 func TestRenameFillGapsExactlyOne(t *testing.T) {
 	files := []string{"1.jpg"}
 	expected := []RenameEntry{
-		{oldName: "1.jpg", newName: "0.jpg"},
+		{OldName: "1.jpg", NewName: "0.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeRenames(files, []int{0}))
 }
@@ -90,7 +163,7 @@ func TestRenameFillGapsExactlyOne(t *testing.T) {
 func TestRenameFillGapsExactlyOneHighNumber(t *testing.T) {
 	files := []string{"5.jpg"}
 	expected := []RenameEntry{
-		{oldName: "5.jpg", newName: "0.jpg"},
+		{OldName: "5.jpg", NewName: "0.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeRenames(files, []int{0, 1, 2, 3, 4}))
 }
@@ -98,7 +171,7 @@ func TestRenameFillGapsExactlyOneHighNumber(t *testing.T) {
 func TestRenameFillGapsExactlyTwoStartHole(t *testing.T) {
 	files := []string{"1.jpg", "2.jpg"}
 	expected := []RenameEntry{
-		{oldName: "2.jpg", newName: "0.jpg"},
+		{OldName: "2.jpg", NewName: "0.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeRenames(files, []int{0}))
 }
@@ -106,7 +179,7 @@ func TestRenameFillGapsExactlyTwoStartHole(t *testing.T) {
 func TestRenameFillGapsExactlyTwoMidHole(t *testing.T) {
 	files := []string{"0.jpg", "2.jpg"}
 	expected := []RenameEntry{
-		{oldName: "2.jpg", newName: "1.jpg"},
+		{OldName: "2.jpg", NewName: "1.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeRenames(files, []int{1}))
 }
@@ -114,7 +187,7 @@ func TestRenameFillGapsExactlyTwoMidHole(t *testing.T) {
 func TestRenameFillGapsExactlyTwoTwoHoles(t *testing.T) {
 	files := []string{"1.jpg", "3.jpg"}
 	expected := []RenameEntry{
-		{oldName: "3.jpg", newName: "0.jpg"},
+		{OldName: "3.jpg", NewName: "0.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeRenames(files, []int{0, 2}))
 }
@@ -122,12 +195,12 @@ func TestRenameFillGapsExactlyTwoTwoHoles(t *testing.T) {
 func TestRenameFillGapsManyMinorVersions(t *testing.T) {
 	files := []string{"0.jpg", "2.jpg", "4-0.jpg", "4-1.jpg", "4-2.jpg", "4-3.jpg", "4-4.jpg", "4-5.jpg"}
 	expected := []RenameEntry{
-		{oldName: "4-0.jpg", newName: "1-0.jpg"},
-		{oldName: "4-1.jpg", newName: "1-1.jpg"},
-		{oldName: "4-2.jpg", newName: "1-2.jpg"},
-		{oldName: "4-3.jpg", newName: "1-3.jpg"},
-		{oldName: "4-4.jpg", newName: "1-4.jpg"},
-		{oldName: "4-5.jpg", newName: "1-5.jpg"},
+		{OldName: "4-0.jpg", NewName: "1-0.jpg"},
+		{OldName: "4-1.jpg", NewName: "1-1.jpg"},
+		{OldName: "4-2.jpg", NewName: "1-2.jpg"},
+		{OldName: "4-3.jpg", NewName: "1-3.jpg"},
+		{OldName: "4-4.jpg", NewName: "1-4.jpg"},
+		{OldName: "4-5.jpg", NewName: "1-5.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeRenames(files, []int{1, 3}))
 }
@@ -135,13 +208,13 @@ func TestRenameFillGapsManyMinorVersions(t *testing.T) {
 func TestRenameFillGapsPenultimateManyMinorVersions(t *testing.T) {
 	files := []string{"0.jpg", "2.jpg", "4-0.jpg", "4-1.jpg", "4-2.jpg", "4-3.jpg", "4-4.jpg", "4-5.jpg", "5.jpg"}
 	expected := []RenameEntry{
-		{oldName: "4-0.jpg", newName: "1-0.jpg"},
-		{oldName: "4-1.jpg", newName: "1-1.jpg"},
-		{oldName: "4-2.jpg", newName: "1-2.jpg"},
-		{oldName: "4-3.jpg", newName: "1-3.jpg"},
-		{oldName: "4-4.jpg", newName: "1-4.jpg"},
-		{oldName: "4-5.jpg", newName: "1-5.jpg"},
-		{oldName: "5.jpg", newName: "3.jpg"},
+		{OldName: "4-0.jpg", NewName: "1-0.jpg"},
+		{OldName: "4-1.jpg", NewName: "1-1.jpg"},
+		{OldName: "4-2.jpg", NewName: "1-2.jpg"},
+		{OldName: "4-3.jpg", NewName: "1-3.jpg"},
+		{OldName: "4-4.jpg", NewName: "1-4.jpg"},
+		{OldName: "4-5.jpg", NewName: "1-5.jpg"},
+		{OldName: "5.jpg", NewName: "3.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeRenames(files, []int{1, 3}))
 }
@@ -149,16 +222,16 @@ func TestRenameFillGapsPenultimateManyMinorVersions(t *testing.T) {
 func TestRenameMajorVersionDigits(t *testing.T) {
 	files := []string{"0.jpg", "1.jpg", "2.jpg", "3.jpg", "4.jpg", "5.jpg", "6.jpg", "7.jpg", "8.jpg", "9.jpg", "10.jpg"}
 	expected := []RenameEntry{
-		{oldName: "0.jpg", newName: "00.jpg"},
-		{oldName: "1.jpg", newName: "01.jpg"},
-		{oldName: "2.jpg", newName: "02.jpg"},
-		{oldName: "3.jpg", newName: "03.jpg"},
-		{oldName: "4.jpg", newName: "04.jpg"},
-		{oldName: "5.jpg", newName: "05.jpg"},
-		{oldName: "6.jpg", newName: "06.jpg"},
-		{oldName: "7.jpg", newName: "07.jpg"},
-		{oldName: "8.jpg", newName: "08.jpg"},
-		{oldName: "9.jpg", newName: "09.jpg"},
+		{OldName: "0.jpg", NewName: "00.jpg"},
+		{OldName: "1.jpg", NewName: "01.jpg"},
+		{OldName: "2.jpg", NewName: "02.jpg"},
+		{OldName: "3.jpg", NewName: "03.jpg"},
+		{OldName: "4.jpg", NewName: "04.jpg"},
+		{OldName: "5.jpg", NewName: "05.jpg"},
+		{OldName: "6.jpg", NewName: "06.jpg"},
+		{OldName: "7.jpg", NewName: "07.jpg"},
+		{OldName: "8.jpg", NewName: "08.jpg"},
+		{OldName: "9.jpg", NewName: "09.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeRenames(files, []int{}))
 }
@@ -166,19 +239,19 @@ func TestRenameMajorVersionDigits(t *testing.T) {
 func TestRenameMinorVersionDigits(t *testing.T) {
 	files := []string{"0.jpg", "1-0.jpg", "2-1.jpg", "2-3.jpg", "3-0.jpg", "3-1.jpg", "3-2.jpg", "3-3.jpg", "3-4.jpg", "3-5.jpg", "3-6.jpg", "3-7.jpg", "3-8.jpg", "3-9.jpg", "3-10.jpg"}
 	expected := []RenameEntry{
-		{oldName: "1-0.jpg", newName: "1.jpg"},
-		{oldName: "2-1.jpg", newName: "2-0.jpg"},
-		{oldName: "2-3.jpg", newName: "2-1.jpg"},
-		{oldName: "3-0.jpg", newName: "3-00.jpg"},
-		{oldName: "3-1.jpg", newName: "3-01.jpg"},
-		{oldName: "3-2.jpg", newName: "3-02.jpg"},
-		{oldName: "3-3.jpg", newName: "3-03.jpg"},
-		{oldName: "3-4.jpg", newName: "3-04.jpg"},
-		{oldName: "3-5.jpg", newName: "3-05.jpg"},
-		{oldName: "3-6.jpg", newName: "3-06.jpg"},
-		{oldName: "3-7.jpg", newName: "3-07.jpg"},
-		{oldName: "3-8.jpg", newName: "3-08.jpg"},
-		{oldName: "3-9.jpg", newName: "3-09.jpg"},
+		{OldName: "1-0.jpg", NewName: "1.jpg"},
+		{OldName: "2-1.jpg", NewName: "2-0.jpg"},
+		{OldName: "2-3.jpg", NewName: "2-1.jpg"},
+		{OldName: "3-0.jpg", NewName: "3-00.jpg"},
+		{OldName: "3-1.jpg", NewName: "3-01.jpg"},
+		{OldName: "3-2.jpg", NewName: "3-02.jpg"},
+		{OldName: "3-3.jpg", NewName: "3-03.jpg"},
+		{OldName: "3-4.jpg", NewName: "3-04.jpg"},
+		{OldName: "3-5.jpg", NewName: "3-05.jpg"},
+		{OldName: "3-6.jpg", NewName: "3-06.jpg"},
+		{OldName: "3-7.jpg", NewName: "3-07.jpg"},
+		{OldName: "3-8.jpg", NewName: "3-08.jpg"},
+		{OldName: "3-9.jpg", NewName: "3-09.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeRenames(files, []int{}))
 }
@@ -192,9 +265,9 @@ func TestComputeStats(t *testing.T) {
 		"foo.jpg", // Invalid
 	}
 	expected := []MetadataStat{
-		{tag: "Foo", files: []string{"0-Foo.jpg", "1-1-Foo, Baz.jpg"}},
-		{tag: "Bar", files: []string{"1-0-Bar.jpg"}},
-		{tag: "Baz", files: []string{"1-1-Foo, Baz.jpg"}},
+		{Tag: "Foo", Files: []string{"0-Foo.jpg", "1-1-Foo, Baz.jpg"}},
+		{Tag: "Bar", Files: []string{"1-0-Bar.jpg"}},
+		{Tag: "Baz", Files: []string{"1-1-Foo, Baz.jpg"}},
 	}
 	
 	actual := ComputeStats(files)
@@ -203,36 +276,36 @@ func TestComputeStats(t *testing.T) {
 
 func TestSortStatsAlphabetical(t *testing.T) {
 	stats := []MetadataStat{
-		{tag: "Foo", files: []string{"1.jpg", "2.jpg"}},
-		{tag: "Bar", files: []string{"3.jpg"}},
-		{tag: "Apple", files: []string{"4.jpg"}},
+		{Tag: "Foo", Files: []string{"1.jpg", "2.jpg"}},
+		{Tag: "Bar", Files: []string{"3.jpg"}},
+		{Tag: "Apple", Files: []string{"4.jpg"}},
 	}
 	
 	SortStatsAlphabetical(stats)
 	
 	expected := []MetadataStat{
-		{tag: "Apple", files: []string{"4.jpg"}},
-		{tag: "Bar", files: []string{"3.jpg"}},
-		{tag: "Foo", files: []string{"1.jpg", "2.jpg"}},
+		{Tag: "Apple", Files: []string{"4.jpg"}},
+		{Tag: "Bar", Files: []string{"3.jpg"}},
+		{Tag: "Foo", Files: []string{"1.jpg", "2.jpg"}},
 	}
 	assert.Equal(t, expected, stats)
 }
 
 func TestSortStatsByFrequency(t *testing.T) {
 	stats := []MetadataStat{
-		{tag: "Apple", files: []string{"4.jpg"}},
-		{tag: "Foo", files: []string{"1.jpg", "2.jpg"}},
-		{tag: "Bar", files: []string{"3.jpg"}},
-		{tag: "Zeta", files: []string{"5.jpg", "6.jpg"}},
+		{Tag: "Apple", Files: []string{"4.jpg"}},
+		{Tag: "Foo", Files: []string{"1.jpg", "2.jpg"}},
+		{Tag: "Bar", Files: []string{"3.jpg"}},
+		{Tag: "Zeta", Files: []string{"5.jpg", "6.jpg"}},
 	}
 	
 	SortStatsByFrequency(stats)
 	
 	expected := []MetadataStat{
-		{tag: "Foo", files: []string{"1.jpg", "2.jpg"}},
-		{tag: "Zeta", files: []string{"5.jpg", "6.jpg"}},
-		{tag: "Apple", files: []string{"4.jpg"}},
-		{tag: "Bar", files: []string{"3.jpg"}},
+		{Tag: "Foo", Files: []string{"1.jpg", "2.jpg"}},
+		{Tag: "Zeta", Files: []string{"5.jpg", "6.jpg"}},
+		{Tag: "Apple", Files: []string{"4.jpg"}},
+		{Tag: "Bar", Files: []string{"3.jpg"}},
 	}
 	assert.Equal(t, expected, stats)
 }
@@ -241,9 +314,9 @@ func TestAppendBasic(t *testing.T) {
 	files := []string{"1-0.jpg", "1-1.jpg", "2-0.jpg", "2-1.jpg", "2-2.jpg"}
 	// Append 2 onto 1
 	expected := []RenameEntry{
-		{oldName: "2-0.jpg", newName: "1-2.jpg"},
-		{oldName: "2-1.jpg", newName: "1-3.jpg"},
-		{oldName: "2-2.jpg", newName: "1-4.jpg"},
+		{OldName: "2-0.jpg", NewName: "1-2.jpg"},
+		{OldName: "2-1.jpg", NewName: "1-3.jpg"},
+		{OldName: "2-2.jpg", NewName: "1-4.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeAppend(files, 2, 1))
 }
@@ -252,8 +325,8 @@ func TestAppendWithTags(t *testing.T) {
 	files := []string{"1-0-Foo.jpg", "1-1-Bar.jpg", "2-0-Baz.jpg", "2-1.jpg"}
 	// Append 2 onto 1
 	expected := []RenameEntry{
-		{oldName: "2-0-Baz.jpg", newName: "1-2-Baz.jpg"},
-		{oldName: "2-1.jpg", newName: "1-3.jpg"},
+		{OldName: "2-0-Baz.jpg", NewName: "1-2-Baz.jpg"},
+		{OldName: "2-1.jpg", NewName: "1-3.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeAppend(files, 2, 1))
 }
@@ -263,9 +336,9 @@ func TestAppendToSingleNoVersion(t *testing.T) {
 	// 1 becomes 1-0, and 2-0 becomes 1-1, etc.
 	files := []string{"1.jpg", "2-0.jpg", "2-1.jpg"}
 	expected := []RenameEntry{
-		{oldName: "1.jpg", newName: "1-0.jpg"},
-		{oldName: "2-0.jpg", newName: "1-1.jpg"},
-		{oldName: "2-1.jpg", newName: "1-2.jpg"},
+		{OldName: "1.jpg", NewName: "1-0.jpg"},
+		{OldName: "2-0.jpg", NewName: "1-1.jpg"},
+		{OldName: "2-1.jpg", NewName: "1-2.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeAppend(files, 2, 1))
 }
@@ -275,8 +348,8 @@ func TestAppendToEmpty(t *testing.T) {
 	// Minor numbers should start from 0 because NoVersion defaults max to -1, which increments to 0 for the first
 	files := []string{"2-0.jpg", "2-1.jpg", "3-0.jpg"}
 	expected := []RenameEntry{
-		{oldName: "2-0.jpg", newName: "1-0.jpg"},
-		{oldName: "2-1.jpg", newName: "1-1.jpg"},
+		{OldName: "2-0.jpg", NewName: "1-0.jpg"},
+		{OldName: "2-1.jpg", NewName: "1-1.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeAppend(files, 2, 1))
 }
@@ -289,17 +362,17 @@ func TestAppendDigitsResized(t *testing.T) {
 		"2-0.jpg", "2-1.jpg",
 	}
 	expected := []RenameEntry{
-		{oldName: "1-0.jpg", newName: "1-00.jpg"},
-		{oldName: "1-1.jpg", newName: "1-01.jpg"},
-		{oldName: "1-2.jpg", newName: "1-02.jpg"},
-		{oldName: "1-3.jpg", newName: "1-03.jpg"},
-		{oldName: "1-4.jpg", newName: "1-04.jpg"},
-		{oldName: "1-5.jpg", newName: "1-05.jpg"},
-		{oldName: "1-6.jpg", newName: "1-06.jpg"},
-		{oldName: "1-7.jpg", newName: "1-07.jpg"},
-		{oldName: "1-8.jpg", newName: "1-08.jpg"},
-		{oldName: "2-0.jpg", newName: "1-09.jpg"},
-		{oldName: "2-1.jpg", newName: "1-10.jpg"},
+		{OldName: "1-0.jpg", NewName: "1-00.jpg"},
+		{OldName: "1-1.jpg", NewName: "1-01.jpg"},
+		{OldName: "1-2.jpg", NewName: "1-02.jpg"},
+		{OldName: "1-3.jpg", NewName: "1-03.jpg"},
+		{OldName: "1-4.jpg", NewName: "1-04.jpg"},
+		{OldName: "1-5.jpg", NewName: "1-05.jpg"},
+		{OldName: "1-6.jpg", NewName: "1-06.jpg"},
+		{OldName: "1-7.jpg", NewName: "1-07.jpg"},
+		{OldName: "1-8.jpg", NewName: "1-08.jpg"},
+		{OldName: "2-0.jpg", NewName: "1-09.jpg"},
+		{OldName: "2-1.jpg", NewName: "1-10.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeAppend(files, 2, 1))
 }
@@ -309,8 +382,8 @@ func TestAppendIgnoreOtherFiles(t *testing.T) {
 	// We verify that since major 2 is not part of the append operation, it gets ignored.
 	files := []string{"1.jpg", "2.jpeg", "3.jpg"}
 	expected := []RenameEntry{
-		{oldName: "1.jpg", newName: "1-0.jpg"},
-		{oldName: "3.jpg", newName: "1-1.jpg"},
+		{OldName: "1.jpg", NewName: "1-0.jpg"},
+		{OldName: "3.jpg", NewName: "1-1.jpg"},
 	}
 	assert.ElementsMatch(t, expected, ComputeAppend(files, 3, 1))
 }
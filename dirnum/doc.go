@@ -0,0 +1,21 @@
+// Package dirnum parses, validates and renumbers directories of image files named according to the dirnum schema:
+//
+//	"0000.jpg", "0001.jpg", etc.                    - Major versions
+//	"0000-0.jpg", "0000-1.jpg", etc.                - Minor versions for grouped files
+//	"0000-note.jpg", "0000-0-note, tag.jpg", etc.   - Comma-separated text tags on file names
+//
+// The package does not print or prompt; operations which change a directory are split into a pure planning step
+// (ComputeRenames, ComputeAppendVersion, PlanExport) which returns what would be done, and an execution step which
+// does it.  Rename plans are applied with ApplyRenames, GitRepo.ApplyRenames when the directory is tracked by git,
+// or Journal.Apply, which also records them so that they can be undone.  These check the whole plan first, stage
+// swapped names through temporary names and reverse the moves already made if one fails, so they should be used
+// rather than renaming files one at a time with RenameFile.  ExportTags carries out an export.  The dirnum command is
+// a thin interface over this package.
+//
+// # Stability
+//
+// The package follows semantic versioning.  Exported identifiers will not be removed or changed incompatibly
+// without a major version bump.  The ErrorCode values and the JSON encoding of ValidationError are part of this
+// guarantee and are safe to match on; the English text of error messages is not and may be reworded at any time.
+// New fields may be added to exported structs, so construct them with field names.
+package dirnum
@@ -0,0 +1,231 @@
+package dirnum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultTrashDir is the subdirectory duplicates are moved into.  It begins with ".dirnum" so that it is not
+// reported as a bad file name.
+const DefaultTrashDir = ".dirnum-trash"
+
+// ValidateTrashDir reports whether name may be used as the trash directory: a name directly within the directory
+// which begins with ".dirnum", like DefaultTrashDir, so that dirnum never validates it or treats it as a group
+// directory
+func ValidateTrashDir(name string) error {
+	if !ownFileRegEx.MatchString(name) || filepath.Base(name) != name {
+		return fmt.Errorf("invalid trash directory %q: it must be a name beginning with .dirnum, such as %s", name, DefaultTrashDir)
+	}
+	return nil
+}
+
+// DuplicateFile is a file within a DuplicateGroup
+type DuplicateFile struct {
+	Name  string `json:"name"`
+	Major int    `json:"major"` // NoVersion if the name could not be parsed
+	Minor int    `json:"minor"` // NoVersion if the file has no minor version or the name could not be parsed
+}
+
+// MarshalJSON renders missing version numbers as null rather than as NoVersion, as ValidationError does
+func (f DuplicateFile) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name  string `json:"name"`
+		Major *int   `json:"major"`
+		Minor *int   `json:"minor"`
+	}{f.Name, jsonVersion(f.Major), jsonVersion(f.Minor)})
+}
+
+// DuplicateGroup is a set of files with byte-identical contents.  The files are ordered by version, so the first
+// is the one to keep.
+type DuplicateGroup struct {
+	Hash  string          `json:"sha256"`
+	Size  int64           `json:"size"`
+	Files []DuplicateFile `json:"files"`
+}
+
+// HashFile returns the hex-encoded SHA-256 hash of a file's contents
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashFiles hashes files within a directory using a pool of workers.  It returns a map from file name to hash.
+func HashFiles(dir string, files []string, workers int) (map[string]string, error) {
+	var mu sync.Mutex
+	hashes := make(map[string]string, len(files))
+	var firstErr error
+	forEach(files, workers, func(f string) {
+		h, err := HashFile(filepath.Join(dir, f))
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		hashes[f] = h
+	})
+	return hashes, firstErr
+}
+
+// FindDuplicates finds duplicates using DefaultSchema
+func FindDuplicates(dir string, files []string, workers int) ([]DuplicateGroup, error) {
+	return DefaultSchema.FindDuplicates(dir, files, workers)
+}
+
+// FindDuplicates finds groups of files with identical contents.  Only files whose size matches another file's are
+// hashed.  The groups are ordered by the version of their first file.
+func (s *Schema) FindDuplicates(dir string, files []string, workers int) ([]DuplicateGroup, error) {
+	bySize := make(map[int64][]string)
+	for _, f := range files {
+		info, err := os.Stat(filepath.Join(dir, f))
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode().IsRegular() {
+			bySize[info.Size()] = append(bySize[info.Size()], f)
+		}
+	}
+	var candidates []string
+	sizes := make(map[string]int64)
+	for size, same := range bySize {
+		if len(same) > 1 {
+			candidates = append(candidates, same...)
+			for _, f := range same {
+				sizes[f] = size
+			}
+		}
+	}
+
+	hashes, err := HashFiles(dir, candidates, workers)
+	if err != nil {
+		return nil, err
+	}
+	byHash := make(map[string][]string)
+	for f, h := range hashes {
+		byHash[h] = append(byHash[h], f)
+	}
+
+	var groups []DuplicateGroup
+	for h, same := range byHash {
+		if len(same) < 2 {
+			continue
+		}
+		g := DuplicateGroup{Hash: h, Size: sizes[same[0]]}
+		for _, f := range same {
+			d := DuplicateFile{Name: f, Major: NoVersion, Minor: NoVersion}
+			if parsed, err := s.Parse(f); err == nil {
+				d.Major, d.Minor = parsed.Major, parsed.Minor
+			}
+			g.Files = append(g.Files, d)
+		}
+		sort.Slice(g.Files, func(i, j int) bool { return duplicateLess(g.Files[i], g.Files[j]) })
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return duplicateLess(groups[i].Files[0], groups[j].Files[0]) })
+	return groups, nil
+}
+
+// duplicateLess orders correctly named files by version, followed by badly named files by name
+func duplicateLess(a, b DuplicateFile) bool {
+	if (a.Major == NoVersion) != (b.Major == NoVersion) {
+		return a.Major != NoVersion
+	}
+	if a.Major != b.Major {
+		return a.Major < b.Major
+	}
+	if a.Minor != b.Minor {
+		return a.Minor < b.Minor
+	}
+	return a.Name < b.Name
+}
+
+// PlanDuplicateRemoval plans the removal of duplicates using DefaultSchema
+func PlanDuplicateRemoval(dir, trashDir string, groups []DuplicateGroup, mergeTags bool) ([]RenameEntry, error) {
+	return DefaultSchema.PlanDuplicateRemoval(dir, trashDir, groups, mergeTags)
+}
+
+// PlanDuplicateRemoval plans moving every file but the first of each group into trashDir, a subdirectory of dir.
+// Nothing is deleted, so applying the plan through a Journal allows it to be undone.  If mergeTags is set, the
+// kept file is renamed to carry the tags of the files it replaces.  trashDir must be accepted by ValidateTrashDir.
+func (s *Schema) PlanDuplicateRemoval(dir, trashDir string, groups []DuplicateGroup, mergeTags bool) ([]RenameEntry, error) {
+	if err := ValidateTrashDir(trashDir); err != nil {
+		return nil, err
+	}
+	trashed := make(map[string]bool)
+	var renames []RenameEntry
+	for _, g := range groups {
+		for _, d := range g.Files[1:] {
+			target, err := uniqueTrashName(dir, trashDir, d.Name, trashed)
+			if err != nil {
+				return nil, err
+			}
+			renames = append(renames, RenameEntry{OldName: d.Name, NewName: target})
+		}
+
+		if !mergeTags {
+			continue
+		}
+		kept, err := s.Parse(g.Files[0].Name)
+		if err != nil {
+			continue // Tags cannot be added to a badly named file
+		}
+		tags := kept.Tags()
+		for _, d := range g.Files[1:] {
+			if other, err := s.Parse(d.Name); err == nil {
+				for _, t := range other.Tags() {
+					if !slices.Contains(tags, t) {
+						tags = append(tags, t)
+					}
+				}
+			}
+		}
+		kept.Descriptor = strings.Join(tags, ", ")
+		if merged := kept.String(); merged != kept.OriginalName {
+			renames = append(renames, RenameEntry{OldName: kept.OriginalName, NewName: merged})
+		}
+	}
+	return renames, nil
+}
+
+// uniqueTrashName chooses a name within the trash directory which is not already in use
+func uniqueTrashName(dir, trashDir, name string, used map[string]bool) (string, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 0; ; i++ {
+		candidate := name
+		if i > 0 {
+			candidate = base + " (" + strconv.Itoa(i) + ")" + ext
+		}
+		target := filepath.Join(trashDir, candidate)
+		if used[target] {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(dir, target)); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return "", fmt.Errorf("cannot check trash for %s: %w", name, err)
+		}
+		used[target] = true
+		return target, nil
+	}
+}
@@ -0,0 +1,69 @@
+package dirnum
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"0012-3.jpg":   "photo",
+		"0140.jpg":     "photo",
+		"0000-foo.jpg": "other",
+		"0001.jpg":     "photx", // Same size, different contents
+		"notes.jpg":    "photo",
+	})
+	files, err := ReadFileNames(dir)
+	assert.NoError(t, err)
+
+	groups, err := FindDuplicates(dir, files, 3)
+	assert.NoError(t, err)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, int64(5), groups[0].Size)
+	assert.Equal(t, []DuplicateFile{
+		{Name: "0012-3.jpg", Major: 12, Minor: 3},
+		{Name: "0140.jpg", Major: 140, Minor: NoVersion},
+		{Name: "notes.jpg", Major: NoVersion, Minor: NoVersion},
+	}, groups[0].Files)
+
+	// Missing versions are null in JSON, as they are for validation errors
+	out, err := json.Marshal(groups[0].Files[1:])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"name": "0140.jpg", "major": 140, "minor": null}, {"name": "notes.jpg", "major": null, "minor": null}]`, string(out))
+}
+
+func TestDuplicateRemovalIsUndoable(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"0-foo.jpg": "a", "1-bar, foo.jpg": "a", "2.jpg": "b"})
+	files, err := ReadFileNames(dir)
+	assert.NoError(t, err)
+	groups, err := FindDuplicates(dir, files, 1)
+	assert.NoError(t, err)
+
+	// The trash must be hidden from validation like dirnum's other files
+	for _, bad := range []string{"trash", "0012", ".dirnum-trash/sub", "../.dirnum-trash"} {
+		_, err = PlanDuplicateRemoval(dir, bad, groups, false)
+		assert.Error(t, err, bad)
+	}
+
+	trash := DefaultTrashDir
+	ren, err := PlanDuplicateRemoval(dir, trash, groups, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []RenameEntry{
+		{OldName: "1-bar, foo.jpg", NewName: filepath.Join(trash, "1-bar, foo.jpg")},
+		{OldName: "0-foo.jpg", NewName: "0-foo, bar.jpg"},
+	}, ren)
+
+	j, err := ReadJournal(dir)
+	assert.NoError(t, err)
+	assert.NoError(t, j.Apply("remove duplicates", ren))
+	assert.Equal(t, map[string]string{"0-foo, bar.jpg": "a", "2.jpg": "b"}, readFiles(t, dir))
+
+	_, err = j.Undo()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"0-foo.jpg": "a", "1-bar, foo.jpg": "a", "2.jpg": "b"}, readFiles(t, dir))
+}
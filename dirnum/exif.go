@@ -0,0 +1,159 @@
+package dirnum
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoCaptureTime is returned when an image does not record when it was taken
+var ErrNoCaptureTime = errors.New("no EXIF capture time")
+
+// EXIF tags used to find the capture time
+const (
+	exifIFDPointerTag   = 0x8769
+	dateTimeOriginalTag = 0x9003
+	exifASCIIType       = 2
+	exifLongType        = 4
+)
+
+// exifTimeLayout is the format of EXIF date and time values
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// ReadCaptureTime reads the EXIF DateTimeOriginal of a JPEG file.  EXIF times have no time zone, so the time is
+// returned in UTC; it is only meaningful when compared with times from the same camera.
+func ReadCaptureTime(path string) (time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	return readCaptureTime(bufio.NewReader(f))
+}
+
+// readCaptureTime scans the segments of a JPEG for an EXIF APP1 segment and reads the capture time from it
+func readCaptureTime(r io.Reader) (time.Time, error) {
+	var marker [2]byte
+	if _, err := io.ReadFull(r, marker[:]); err != nil || marker != [2]byte{0xFF, 0xD8} {
+		return time.Time{}, fmt.Errorf("not a JPEG file")
+	}
+	for {
+		if _, err := io.ReadFull(r, marker[:]); err != nil {
+			return time.Time{}, ErrNoCaptureTime
+		}
+		if marker[0] != 0xFF {
+			return time.Time{}, fmt.Errorf("invalid JPEG segment marker")
+		}
+		// Image data follows the start-of-scan marker, so there are no more metadata segments
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return time.Time{}, ErrNoCaptureTime
+		}
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil || length < 2 {
+			return time.Time{}, fmt.Errorf("invalid JPEG segment length")
+		}
+		segment := make([]byte, length-2)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return time.Time{}, fmt.Errorf("truncated JPEG segment")
+		}
+		if marker[1] == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return parseExifCaptureTime(segment[6:])
+		}
+	}
+}
+
+// parseExifCaptureTime reads DateTimeOriginal from EXIF data, which is laid out as a TIFF file
+func parseExifCaptureTime(tiff []byte) (time.Time, error) {
+	if len(tiff) < 8 {
+		return time.Time{}, fmt.Errorf("truncated EXIF data")
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return time.Time{}, fmt.Errorf("invalid EXIF byte order")
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return time.Time{}, fmt.Errorf("invalid EXIF header")
+	}
+
+	// The capture time is in the EXIF sub-IFD, which IFD0 points to
+	ifd0 := order.Uint32(tiff[4:])
+	pointer, found := findExifTag(tiff, order, ifd0, exifIFDPointerTag)
+	if !found || order.Uint16(pointer[2:]) != exifLongType {
+		return time.Time{}, ErrNoCaptureTime
+	}
+	entry, found := findExifTag(tiff, order, order.Uint32(pointer[8:]), dateTimeOriginalTag)
+	if !found || order.Uint16(entry[2:]) != exifASCIIType {
+		return time.Time{}, ErrNoCaptureTime
+	}
+
+	// ASCII values of more than four bytes are stored at an offset rather than in the entry
+	count := order.Uint32(entry[4:])
+	value := entry[8:12]
+	if count > 4 {
+		offset := order.Uint32(entry[8:])
+		if uint64(offset)+uint64(count) > uint64(len(tiff)) {
+			return time.Time{}, fmt.Errorf("truncated EXIF data")
+		}
+		value = tiff[offset : offset+count]
+	}
+	s := strings.TrimRight(string(value), "\x00 ")
+	t, err := time.Parse(exifTimeLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid EXIF capture time %q", s)
+	}
+	return t, nil
+}
+
+// findExifTag returns the 12-byte entry for a tag within the IFD at the given offset
+func findExifTag(tiff []byte, order binary.ByteOrder, offset uint32, tag uint16) ([]byte, bool) {
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return nil, false
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		start := uint64(offset) + 2 + uint64(i)*12
+		if start+12 > uint64(len(tiff)) {
+			return nil, false
+		}
+		entry := tiff[start : start+12]
+		if order.Uint16(entry) == tag {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// ReadCaptureTimes reads the capture times of the JPEG files among files using a pool of workers.  Files without
+// a capture time are omitted from the result.
+func ReadCaptureTimes(dir string, files []string, workers int) map[string]time.Time {
+	var jpegs []string
+	for _, f := range files {
+		if ext := strings.ToLower(filepath.Ext(f)); ext == ".jpg" || ext == ".jpeg" {
+			jpegs = append(jpegs, f)
+		}
+	}
+
+	var mu sync.Mutex
+	times := make(map[string]time.Time)
+	forEach(jpegs, workers, func(f string) {
+		if t, err := ReadCaptureTime(filepath.Join(dir, f)); err == nil {
+			mu.Lock()
+			times[f] = t
+			mu.Unlock()
+		}
+	})
+	return times
+}
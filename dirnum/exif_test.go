@@ -0,0 +1,105 @@
+package dirnum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exifJPEG encodes a small JPEG with an EXIF segment recording the given DateTimeOriginal
+func exifJPEG(t *testing.T, order binary.ByteOrder, taken string) []byte {
+	// TIFF header, IFD0 holding only the EXIF pointer, the EXIF IFD holding only DateTimeOriginal, then its value
+	var tiff bytes.Buffer
+	if order == binary.LittleEndian {
+		tiff.WriteString("II")
+	} else {
+		tiff.WriteString("MM")
+	}
+	write := func(v any) { assert.NoError(t, binary.Write(&tiff, order, v)) }
+	type entry struct {
+		Tag, Type     uint16
+		Count, Offset uint32
+	}
+	write(uint16(42))
+	write(uint32(8))
+	write(uint16(1))
+	write(entry{exifIFDPointerTag, exifLongType, 1, 26})
+	write(uint32(0))
+	write(uint16(1))
+	write(entry{dateTimeOriginalTag, exifASCIIType, uint32(len(taken) + 1), 44})
+	write(uint32(0))
+	tiff.WriteString(taken + "\x00")
+
+	var img bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&img, testImage(8, 8, false), nil))
+	app1 := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	var out bytes.Buffer
+	out.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	assert.NoError(t, binary.Write(&out, binary.BigEndian, uint16(len(app1)+2)))
+	out.Write(app1)
+	out.Write(img.Bytes()[2:])
+	return out.Bytes()
+}
+
+func TestReadCaptureTime(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "0.jpg"), exifJPEG(t, binary.LittleEndian, "2019:05:01 10:30:00"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "1.jpg"), exifJPEG(t, binary.BigEndian, "2019:04:30 08:00:00"), 0644))
+	saveImage(t, filepath.Join(dir, "2.jpg"), testImage(8, 8, false))
+	writeFiles(t, dir, map[string]string{"3.jpg": "not a jpeg", "4.gif": "GIF89a"})
+
+	taken, err := ReadCaptureTime(filepath.Join(dir, "0.jpg"))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 5, 1, 10, 30, 0, 0, time.UTC), taken)
+
+	_, err = ReadCaptureTime(filepath.Join(dir, "2.jpg"))
+	assert.ErrorIs(t, err, ErrNoCaptureTime)
+	_, err = ReadCaptureTime(filepath.Join(dir, "3.jpg"))
+	assert.Error(t, err)
+
+	files, err := ReadFileNames(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Time{
+		"0.jpg": time.Date(2019, 5, 1, 10, 30, 0, 0, time.UTC),
+		"1.jpg": time.Date(2019, 4, 30, 8, 0, 0, 0, time.UTC),
+	}, ReadCaptureTimes(dir, files, 2))
+}
+
+func TestChronology(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 12, 0, 0, 0, time.UTC) }
+	files := []string{"1.jpg", "2-0.jpg", "2-1.jpg", "3.jpg", "4.gif", "5.jpg", "6-tag.jpg"}
+	times := map[string]time.Time{
+		"1.jpg":     day(1),
+		"2-0.jpg":   day(9),
+		"2-1.jpg":   day(2), // A group is as old as its earliest file
+		"3.jpg":     day(20),
+		"5.jpg":     day(4),
+		"6-tag.jpg": day(5),
+	}
+
+	// Moving major 3 alone puts everything in order, so major 5 and major 6 are not reported
+	errors := ValidateChronology(files, times)
+	assert.Len(t, errors.All(), 1)
+	e := errors.All()[0]
+	assert.Equal(t, CodeChronology, e.Code)
+	assert.Equal(t, "3.jpg", e.File)
+	assert.Equal(t, []string{"5.jpg"}, e.Related)
+	assert.True(t, e.Code.Fixable())
+
+	// Major 4 has no capture time, so it keeps its number
+	assert.Equal(t, []RenameEntry{
+		{OldName: "5.jpg", NewName: "3.jpg"},
+		{OldName: "6-tag.jpg", NewName: "5-tag.jpg"},
+		{OldName: "3.jpg", NewName: "6.jpg"},
+	}, ComputeChronologicalOrder(files, times))
+
+	delete(times, "3.jpg")
+	assert.Empty(t, ValidateChronology(files, times))
+	assert.Empty(t, ComputeChronologicalOrder(files, times))
+}
@@ -1,64 +1,62 @@
-package main
+package dirnum
 
 import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
+// PlanExport plans an export using DefaultSchema
+func PlanExport(files []string, prefix string, minCount int) map[string][]string {
+	return DefaultSchema.PlanExport(files, prefix, minCount)
+}
+
 // PlanExport determines which files should be copied to which subdirectories based on tags.
 // It returns a map of tag name to a slice of filenames.
-func PlanExport(files []string, prefix string, minCount int) map[string][]string {
-	stats := ComputeStats(files)
+func (s *Schema) PlanExport(files []string, prefix string, minCount int) map[string][]string {
+	stats := s.ComputeStats(files)
 
 	exportPlan := make(map[string][]string)
 
 	for _, stat := range stats {
-		if !strings.HasPrefix(stat.tag, prefix) {
+		if !strings.HasPrefix(stat.Tag, prefix) {
 			continue
 		}
 
-		if len(stat.files) < minCount {
+		if len(stat.Files) < minCount {
 			continue
 		}
 
 		// Find the major versions of the files that explicitly have this tag
 		majorVersions := make(map[int]bool)
-		for _, f := range stat.files {
-			parsed, err := ParseFileName(f)
+		for _, f := range stat.Files {
+			parsed, err := s.Parse(f)
 			if err == nil {
-				majorVersions[parsed.major] = true
+				majorVersions[parsed.Major] = true
 			}
 		}
 
 		// Find all files that share these major versions
 		var filesToExport []string
 		for _, f := range files {
-			parsed, err := ParseFileName(f)
-			if err == nil && majorVersions[parsed.major] {
+			parsed, err := s.Parse(f)
+			if err == nil && majorVersions[parsed.Major] {
 				filesToExport = append(filesToExport, f)
 			}
 		}
 
 		if len(filesToExport) > 0 {
-			exportPlan[stat.tag] = filesToExport
+			exportPlan[stat.Tag] = filesToExport
 		}
 	}
 
 	return exportPlan
 }
 
-// ExportTags copies files into subdirectories based on their tags and their associated major versions.
-func ExportTags(dir string, files []string, prefix string, minCount int) error {
-	exportPlan := PlanExport(files, prefix, minCount)
-
-	if len(exportPlan) == 0 {
-		fmt.Println("No tags matching the given prefix were found.")
-		return nil
-	}
-
-	// Check for conflicting directories
+// ExportConflicts returns the tags in the plan whose target subdirectories already exist
+func ExportConflicts(dir string, exportPlan map[string][]string) []string {
 	var conflictingDirs []string
 	for tag := range exportPlan {
 		targetDir := filepath.Join(dir, tag)
@@ -67,24 +65,17 @@ func ExportTags(dir string, files []string, prefix string, minCount int) error {
 			conflictingDirs = append(conflictingDirs, tag)
 		}
 	}
+	sort.Strings(conflictingDirs)
+	return conflictingDirs
+}
 
-	if len(conflictingDirs) > 0 {
+// ExportTags copies files into subdirectories based on their tags and their associated major versions, as
+// determined by PlanExport.  If progress is not nil it is called before each file is copied.
+func ExportTags(dir string, exportPlan map[string][]string, progress func(tag, file string)) error {
+	if conflictingDirs := ExportConflicts(dir, exportPlan); len(conflictingDirs) > 0 {
 		return fmt.Errorf("cannot proceed, the following matching subdirectories already exist: %s", strings.Join(conflictingDirs, ", "))
 	}
 
-	// Count totals for prompt
-	numFiles := 0
-	for _, filesToExport := range exportPlan {
-		numFiles += len(filesToExport)
-	}
-
-	// Prompt user for confirmation
-	q := fmt.Sprintf("This will create %d subdirectories containing a total of %d files.  Continue?", len(exportPlan), numFiles)
-	if !prompt(q) {
-		return nil
-	}
-
-	// Execute the plan
 	for tag, filesToExport := range exportPlan {
 		targetDir := filepath.Join(dir, tag)
 		if err := os.MkdirAll(targetDir, 0755); err != nil {
@@ -94,7 +85,9 @@ func ExportTags(dir string, files []string, prefix string, minCount int) error {
 		for _, f := range filesToExport {
 			src := filepath.Join(dir, f)
 			dst := filepath.Join(targetDir, f)
-			fmt.Printf("Copying %s to %s\n", f, filepath.Join(tag, f))
+			if progress != nil {
+				progress(tag, f)
+			}
 			if err := CopyFile(src, dst); err != nil {
 				return fmt.Errorf("failed to copy %s to %s: %w", src, dst, err)
 			}
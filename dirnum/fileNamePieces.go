@@ -0,0 +1,187 @@
+package dirnum
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const NoVersion = -99 // Indicates a file with no minor version
+
+// FileNamePieces is a parsed file name.  Modifying the fields and calling String produces the new file name.
+//
+// The version of a file is an ordered list of numeric components, such as 12, 3 and 1 for 0012-03-01.jpg.  The
+// first two are Major and Minor and any deeper ones are in Sub; Versions and SetVersions treat them as one list.
+type FileNamePieces struct {
+	Major, Minor             int   // Minor is NoVersion if the file has no minor version
+	MajorDigits, MinorDigits int   // The number of digits to zero-pad the version numbers to
+	Sub                      []int // The version components below the minor version; empty unless Minor is set
+	SubDigits                []int // The number of digits to zero-pad each of Sub to
+	OriginalName             string
+	Descriptor               string // The text tags, without the leading separator
+	Extension                string // The normalized extension, without the leading dot
+
+	schema *Schema // The schema the name was parsed with; nil for DefaultSchema
+}
+
+// Versions returns the version components in order: the major number, then the minor number and the components
+// below it if the file has them
+func (f *FileNamePieces) Versions() []int {
+	v := []int{f.Major}
+	if f.Minor != NoVersion {
+		v = append(v, f.Minor)
+		v = append(v, f.Sub...)
+	}
+	return v
+}
+
+// Digits returns the number of digits each of the components returned by Versions is zero-padded to
+func (f *FileNamePieces) Digits() []int {
+	d := []int{f.MajorDigits}
+	if f.Minor != NoVersion {
+		d = append(d, f.MinorDigits)
+		for i := range f.Sub {
+			if i < len(f.SubDigits) {
+				d = append(d, f.SubDigits[i])
+			} else {
+				d = append(d, 0)
+			}
+		}
+	}
+	return d
+}
+
+// SetVersions replaces the version components, which must include at least the major number, and the number of
+// digits each is padded to.  Missing digit counts pad to no particular width.
+func (f *FileNamePieces) SetVersions(versions, digits []int) {
+	digit := func(i int) int {
+		if i < len(digits) {
+			return digits[i]
+		}
+		return 0
+	}
+	f.Major, f.MajorDigits = versions[0], digit(0)
+	f.Minor, f.MinorDigits, f.Sub, f.SubDigits = NoVersion, 0, nil, nil
+	if len(versions) > 1 {
+		f.Minor, f.MinorDigits = versions[1], digit(1)
+	}
+	for i := 2; i < len(versions); i++ {
+		f.Sub = append(f.Sub, versions[i])
+		f.SubDigits = append(f.SubDigits, digit(i))
+	}
+}
+
+// FormatVersion writes version components the way dirnum's messages show them, such as "12-3-1"
+func FormatVersion(versions []int) string {
+	parts := make([]string, len(versions))
+	for i, v := range versions {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, "-")
+}
+
+var versionRegEx = regexp.MustCompile(`^[0-9]+([-.][0-9]+)*$`)
+
+// ParseVersion parses version components separated by '-' or '.', such as "12-3-1"
+func ParseVersion(s string) ([]int, error) {
+	if !versionRegEx.MatchString(s) {
+		return nil, fmt.Errorf("invalid version %q", s)
+	}
+	var versions []int
+	for _, p := range strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '.' }) {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q: %w", s, err)
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// compareVersions orders version components lexicographically, with a version before those it is a prefix of
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// hasVersionPrefix reports whether the versions start with the given components
+func hasVersionPrefix(versions, prefix []int) bool {
+	return len(versions) >= len(prefix) && compareVersions(versions[:len(prefix)], prefix) == 0
+}
+
+// String formats the file name using the schema it was parsed with
+func (f *FileNamePieces) String() string {
+	return f.Schema().Format(f)
+}
+
+// Schema returns the schema the file name was parsed with
+func (f *FileNamePieces) Schema() *Schema {
+	if f.schema == nil {
+		return DefaultSchema
+	}
+	return f.schema
+}
+
+// Tags splits the descriptor into its comma-separated tags
+func (f *FileNamePieces) Tags() []string {
+	tags := []string{}
+	for _, t := range strings.Split(f.Descriptor, ",") {
+		t = strings.TrimSpace(t)
+		if len(t) > 0 {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// ParseFileName splits a file name into its version numbers, descriptor and extension using DefaultSchema
+func ParseFileName(f string) (*FileNamePieces, error) {
+	return DefaultSchema.Parse(f)
+}
+
+// ParseFileNames parses every correctly named file using DefaultSchema
+func ParseFileNames(fileNames []string) PFnpSlice {
+	return DefaultSchema.ParseFileNames(fileNames)
+}
+
+// ParseFileNames parses every correctly named file, sorted by version.  Files which do not match
+// the naming schema are skipped.
+func (s *Schema) ParseFileNames(fileNames []string) PFnpSlice {
+	files := make(PFnpSlice, 0)
+	for _, f := range fileNames {
+		n, err := s.Parse(f)
+		if err == nil {
+			// Don't try to rename files which aren't named correctly.  Errors are displayed
+			// before this function and controlled by the quiet flag.
+			files = append(files, n)
+		}
+	}
+
+	// Sort the list by version
+	sort.Stable(files)
+	return files
+}
+
+// PFnpSlice represents a set of file names that can be sorted by version
+type PFnpSlice []*FileNamePieces
+
+func (s PFnpSlice) Len() int {
+	return len(s)
+}
+
+func (s PFnpSlice) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s PFnpSlice) Less(i, j int) bool {
+	return compareVersions(s[i].Versions(), s[j].Versions()) < 0
+}
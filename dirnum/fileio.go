@@ -0,0 +1,81 @@
+package dirnum
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// RenameFile renames a file within a directory
+func RenameFile(oldName, newName, dirName string) error {
+	oldPath := filepath.Join(dirName, oldName)
+	newPath := filepath.Join(dirName, newName)
+	return os.Rename(oldPath, newPath)
+}
+
+// ReadFileNames lists the files within a directory, skipping subdirectories, dirnum's own files and those ignored
+// by LoadIgnore: the default patterns, .dirnumignore files and the extra ignore patterns given
+func ReadFileNames(dir string, ignore ...string) ([]string, error) {
+	files, _, err := ReadEntries(dir, ignore...)
+	return files, err
+}
+
+// ReadEntries lists the files and the subdirectories within a directory separately, skipping ignored names as
+// ReadFileNames does
+func ReadEntries(dir string, ignore ...string) (files, dirs []string, err error) {
+	files = make([]string, 0)
+	m, err := LoadIgnore(dir, ignore...)
+	if err != nil {
+		return files, nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return files, nil, err
+	}
+	for _, e := range entries {
+		n := e.Name()
+		if m.Match(filepath.Join(dir, n), e.IsDir()) {
+			continue
+		}
+		if e.IsDir() {
+			dirs = append(dirs, n)
+		} else {
+			files = append(files, n)
+		}
+	}
+	return files, dirs, nil
+}
+
+// readAllNames lists every entry of a directory, including ignored ones
+func readAllNames(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.Name()
+	}
+	return names, nil
+}
+
+// CopyFile copies the contents of src to a new file dst
+func CopyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,213 @@
+package dirnum
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrNotGitWorkTree is returned by OpenGitRepo for directories outside any git work tree
+var ErrNotGitWorkTree = errors.New("not in a git work tree")
+
+// GitRepo is the git work tree containing a directory.  It runs the git command, which must be installed.
+type GitRepo struct {
+	Root string // The top level of the work tree
+}
+
+// OpenGitRepo finds the git work tree containing dir
+func OpenGitRepo(dir string) (*GitRepo, error) {
+	out, err := runGit(dir, "rev-parse", "--is-inside-work-tree", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dir, ErrNotGitWorkTree)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || lines[0] != "true" {
+		return nil, fmt.Errorf("%s: %w", dir, ErrNotGitWorkTree)
+	}
+	return &GitRepo{Root: lines[1]}, nil
+}
+
+// runGit runs git within dir and returns its output, or an error including what git printed to stderr
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+// gitPaths runs a git command which lists NUL-separated paths and returns them as a set
+func gitPaths(dir string, args ...string) (map[string]bool, error) {
+	out, err := runGit(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]bool)
+	for _, p := range strings.Split(out, "\x00") {
+		if p != "" {
+			paths[p] = true
+		}
+	}
+	return paths, nil
+}
+
+// gitEntries runs a git command which lists NUL-separated entries of the form "<fields>\t<path>", such as ls-files
+// -s or ls-tree, and returns the field at index field of each path
+func gitEntries(dir string, field int, args ...string) (map[string]string, error) {
+	out, err := runGit(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]string)
+	for _, e := range strings.Split(out, "\x00") {
+		meta, p, found := strings.Cut(e, "\t")
+		if fields := strings.Fields(meta); found && field < len(fields) {
+			entries[p] = fields[field]
+		}
+	}
+	return entries, nil
+}
+
+// IndexedFiles lists the files directly within dir as they are in the index, that is as they will be committed,
+// skipping the names which ReadFileNames would ignore
+func (g *GitRepo) IndexedFiles(dir string, ignore ...string) ([]string, error) {
+	m, err := LoadIgnore(dir, ignore...)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := gitPaths(dir, "ls-files", "-z", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(paths))
+	for p := range paths {
+		if !strings.Contains(p, "/") && !m.Match(filepath.Join(dir, p), false) {
+			files = append(files, p)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ChangedFiles lists the files below dir, as paths relative to it, which are staged for the next commit.  Unless
+// stagedOnly is set, it also lists files with unstaged changes and untracked files which are not ignored by git.
+// Deleted files are not listed.
+func (g *GitRepo) ChangedFiles(dir string, stagedOnly bool) ([]string, error) {
+	commands := [][]string{{"diff", "--cached", "--name-only", "-z", "--relative", "--diff-filter=ACMR", "--", "."}}
+	if !stagedOnly {
+		commands = append(commands,
+			[]string{"diff", "--name-only", "-z", "--relative", "--diff-filter=ACMR", "--", "."},
+			[]string{"ls-files", "-z", "--others", "--exclude-standard", "--", "."})
+	}
+	changed := make(map[string]bool)
+	for _, args := range commands {
+		paths, err := gitPaths(dir, args...)
+		if err != nil {
+			return nil, err
+		}
+		for p := range paths {
+			changed[p] = true
+		}
+	}
+	files := make([]string, 0, len(changed))
+	for f := range changed {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ApplyRenames renames files within dir like the package-level ApplyRenames, but moves files tracked by git with
+// "git mv" so that their history follows them.  Untracked files are renamed directly.  It refuses to rename any
+// tracked file with uncommitted changes, staged or not, so that the rename is not mixed up with other changes.  Staged
+// renames, such as those made by an earlier call, do not count as changes, so that they can be undone before they
+// are committed.
+func (g *GitRepo) ApplyRenames(dir string, renames []RenameEntry) error {
+	move, err := g.mover(dir, renames)
+	if err != nil {
+		return err
+	}
+	if err := applyRenames(dir, renames, move, nil); err != nil {
+		return err
+	}
+	return updateManifest(dir, renames)
+}
+
+// mover checks that none of the files renamed has uncommitted changes and returns a function which moves files
+// within dir, using git mv for tracked files
+func (g *GitRepo) mover(dir string, renames []RenameEntry) (func(oldName, newName string) error, error) {
+	modified, err := g.modifiedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	var dirty []string
+	for _, r := range renames {
+		if modified[r.OldName] {
+			dirty = append(dirty, r.OldName)
+		}
+	}
+	if len(dirty) > 0 {
+		return nil, fmt.Errorf("cannot rename files with uncommitted changes; commit or stash them first: %s", strings.Join(dirty, ", "))
+	}
+
+	tracked, err := gitPaths(dir, "ls-files", "-z", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	return func(oldName, newName string) error {
+		if !tracked[oldName] {
+			return RenameFile(oldName, newName, dir)
+		}
+		if _, err := runGit(dir, "mv", "--", oldName, newName); err != nil {
+			return err
+		}
+		// A file staged through a temporary name is moved on from there with git mv as well
+		tracked[newName] = true
+		return nil
+	}, nil
+}
+
+// modifiedFiles returns the set of files below dir, relative to it, whose contents differ from the last commit,
+// either in the work tree or in the index.  A file staged with contents which the last commit holds under any name,
+// as git mv leaves it, has only been renamed and is not included.
+func (g *GitRepo) modifiedFiles(dir string) (map[string]bool, error) {
+	modified, err := gitPaths(dir, "diff", "--name-only", "-z", "--relative", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	staged, err := gitPaths(dir, "diff", "--cached", "--name-only", "-z", "--relative", "--", ".")
+	if err != nil || len(staged) == 0 {
+		return modified, err
+	}
+
+	committed := make(map[string]bool)
+	if _, err := runGit(dir, "rev-parse", "--verify", "-q", "HEAD"); err == nil {
+		blobs, err := gitEntries(g.Root, 2, "ls-tree", "-r", "-z", "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range blobs {
+			committed[b] = true
+		}
+	}
+	indexed, err := gitEntries(dir, 1, "ls-files", "-s", "-z", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	for p := range staged {
+		if !committed[indexed[p]] {
+			modified[p] = true
+		}
+	}
+	return modified, nil
+}
@@ -0,0 +1,125 @@
+package dirnum
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gitTestRepo creates a git repository with the given files committed
+func gitTestRepo(t *testing.T, files map[string]string) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+	writeFiles(t, dir, files)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		_, err := runGit(dir, args...)
+		assert.NoError(t, err)
+	}
+	return dir
+}
+
+func TestGitChangedFiles(t *testing.T) {
+	_, err := OpenGitRepo(t.TempDir())
+	assert.ErrorIs(t, err, ErrNotGitWorkTree)
+
+	dir := gitTestRepo(t, map[string]string{"0.jpg": "a", "1.jpg": "b"})
+	repo, err := OpenGitRepo(dir)
+	assert.NoError(t, err)
+
+	writeFiles(t, dir, map[string]string{"1.jpg": "changed", "2.jpg": "c", "3.jpg": "d"})
+	_, err = runGit(dir, "add", "2.jpg")
+	assert.NoError(t, err)
+
+	staged, err := repo.ChangedFiles(dir, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2.jpg"}, staged)
+	changed, err := repo.ChangedFiles(dir, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.jpg", "2.jpg", "3.jpg"}, changed)
+
+	errors, _ := ValidateFileNames([]string{"0.jpg", "1.jpg", "2.jpg", "2-xy.jpg", "bad.jpg"}, true, true)
+	// Both halves of the duplicate involving the staged file are reported, but not the unrelated bad name
+	involving := errors.Involving(staged)
+	assert.Len(t, involving, 2)
+	assert.Equal(t, CodeOverriddenMajor, involving["2-xy.jpg"][0].Code)
+}
+
+func TestGitApplyRenames(t *testing.T) {
+	dir := gitTestRepo(t, map[string]string{"0.jpg": "a", "1.jpg": "b", "2.jpg": "c"})
+	repo, err := OpenGitRepo(dir)
+	assert.NoError(t, err)
+	writeFiles(t, dir, map[string]string{"5.jpg": "untracked"})
+
+	// Swapping two files goes through temporary names, which must also be moved with git
+	assert.NoError(t, repo.ApplyRenames(dir, []RenameEntry{
+		{OldName: "0.jpg", NewName: "1.jpg"},
+		{OldName: "1.jpg", NewName: "0.jpg"},
+		{OldName: "5.jpg", NewName: "3.jpg"},
+	}))
+	assert.Equal(t, map[string]string{"0.jpg": "b", "1.jpg": "a", "2.jpg": "c", "3.jpg": "untracked"}, readFiles(t, dir))
+	tracked, err := gitPaths(dir, "ls-files", "-z")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"0.jpg": true, "1.jpg": true, "2.jpg": true}, tracked)
+
+	// Tracked files with uncommitted changes are not renamed
+	writeFiles(t, dir, map[string]string{"2.jpg": "changed"})
+	err = repo.ApplyRenames(dir, []RenameEntry{{OldName: "2.jpg", NewName: "4.jpg"}})
+	assert.ErrorContains(t, err, "uncommitted changes")
+	_, err = os.Stat(filepath.Join(dir, "2.jpg"))
+	assert.NoError(t, err)
+}
+
+func TestGitUndoRenames(t *testing.T) {
+	dir := gitTestRepo(t, map[string]string{"0.jpg": "a", "1.jpg": "b", "2.jpg": "c"})
+	repo, err := OpenGitRepo(dir)
+	assert.NoError(t, err)
+	j, err := ReadJournal(dir)
+	assert.NoError(t, err)
+	j.Git = repo
+
+	// The renames staged by git mv are not uncommitted changes, so a second operation can follow before a commit
+	assert.NoError(t, j.Apply("renumber", []RenameEntry{{"0.jpg", "1.jpg"}, {"1.jpg", "0.jpg"}}))
+	assert.NoError(t, j.Apply("fix", []RenameEntry{{"2.jpg", "3.jpg"}}))
+	_, err = j.Undo()
+	assert.NoError(t, err)
+	_, err = j.Undo()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"0.jpg": "a", "1.jpg": "b", "2.jpg": "c"}, readFiles(t, dir))
+	status, err := runGit(dir, "status", "--porcelain", "--untracked-files=no")
+	assert.NoError(t, err)
+	assert.Empty(t, status)
+
+	// Staged changes to contents still prevent renames
+	writeFiles(t, dir, map[string]string{"0.jpg": "changed"})
+	_, err = runGit(dir, "add", "0.jpg")
+	assert.NoError(t, err)
+	assert.ErrorContains(t, j.Apply("fix", []RenameEntry{{"0.jpg", "5.jpg"}}), "uncommitted changes")
+}
+
+func TestGitIndexedFiles(t *testing.T) {
+	dir := gitTestRepo(t, map[string]string{"0.jpg": "a", "1.jpg": "b", ".gitignore": ""})
+	repo, err := OpenGitRepo(dir)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	writeFiles(t, dir, map[string]string{"sub/2.jpg": "c"})
+	_, err = runGit(dir, "add", "sub")
+	assert.NoError(t, err)
+
+	// A rename made in the work tree but not staged is not seen
+	assert.NoError(t, os.Rename(filepath.Join(dir, "1.jpg"), filepath.Join(dir, "3.jpg")))
+	_, err = runGit(dir, "mv", "0.jpg", "4.jpg")
+	assert.NoError(t, err)
+	files, err := repo.IndexedFiles(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.jpg", "4.jpg"}, files)
+}
@@ -0,0 +1,47 @@
+package dirnum
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// CodeGroupMismatch is reported for files in a group directory which belong to a different major group
+const CodeGroupMismatch ErrorCode = "group-mismatch" // A file in a group directory has a different major number
+
+// Group directories are named by their major number alone, such as "0012"
+var groupDirRegEx = regexp.MustCompile(`^[0-9]+$`)
+
+// GroupDirectoryMajor returns the major number of a group directory, a subdirectory holding the minor versions of
+// one major group, and false if the name is not that of a group directory
+func GroupDirectoryMajor(name string) (int, bool) {
+	if !groupDirRegEx.MatchString(name) {
+		return NoVersion, false
+	}
+	major, err := strconv.Atoi(name)
+	if err != nil {
+		return NoVersion, false
+	}
+	return major, true
+}
+
+// ReadGroupFiles lists the files within the group directories among dirs, as paths relative to dir such as
+// "0012/0012-0.jpg", skipping ignored names as ReadFileNames does.  Passing them to ValidateFileNames along with the
+// files directly within dir validates each group directory as part of the numbering.  Subdirectories which are not
+// group directories are skipped.
+func ReadGroupFiles(dir string, dirs []string, ignore ...string) ([]string, error) {
+	files := make([]string, 0)
+	for _, d := range dirs {
+		if _, isGroup := GroupDirectoryMajor(d); !isGroup {
+			continue
+		}
+		names, err := ReadFileNames(filepath.Join(dir, d), ignore...)
+		if err != nil {
+			return files, err
+		}
+		for _, n := range names {
+			files = append(files, filepath.Join(d, n))
+		}
+	}
+	return files, nil
+}
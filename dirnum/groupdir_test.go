@@ -0,0 +1,45 @@
+package dirnum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupDirectories(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"0002", "person_bob", "3x"} {
+		assert.NoError(t, os.Mkdir(filepath.Join(dir, d), 0755))
+	}
+	writeFiles(t, dir, map[string]string{"0.jpg": "", "1.jpg": "", "4.jpg": ""})
+	writeFiles(t, filepath.Join(dir, "0002"), map[string]string{"2-0.jpg": "", "2-1.jpg": "", "5.jpg": "", "Thumbs.db": ""})
+	writeFiles(t, filepath.Join(dir, "person_bob"), map[string]string{"0.jpg": ""})
+
+	files, dirs, err := ReadEntries(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0.jpg", "1.jpg", "4.jpg"}, files)
+	assert.Equal(t, []string{"0002", "3x", "person_bob"}, dirs)
+
+	groupFiles, err := ReadGroupFiles(dir, dirs)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0002/2-0.jpg", "0002/2-1.jpg", "0002/5.jpg"}, groupFiles)
+
+	// The group directory fills major 2, so only 3 is unused
+	errors, unused := ValidateFileNames(append(files, groupFiles...), false, true)
+	assert.Equal(t, []int{3}, unused)
+	assert.Equal(t, CodeGroupMismatch, errors["0002/5.jpg"][0].Code)
+	assert.Len(t, errors.All(), 2)
+	assert.Equal(t, CodeMajorGap, errors["4.jpg"][0].Code)
+
+	// Renumbering only moves the files directly within the directory, and directories are never renamed
+	assert.Equal(t, []RenameEntry{{OldName: "4.jpg", NewName: "3.jpg"}}, ComputeRenames(files, unused))
+	assert.Error(t, ApplyRenames(dir, []RenameEntry{{OldName: "0002", NewName: "0003"}}))
+
+	major, isGroup := GroupDirectoryMajor("0012")
+	assert.True(t, isGroup)
+	assert.Equal(t, 12, major)
+	_, isGroup = GroupDirectoryMajor("12-tag")
+	assert.False(t, isGroup)
+}
@@ -0,0 +1,223 @@
+package dirnum
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the name of the files listing patterns of names to ignore, in the syntax of .gitignore
+const IgnoreFileName = ".dirnumignore"
+
+// DefaultIgnorePatterns are ignored in every directory: metadata written by operating systems, file managers and
+// NAS indexers, editor backup and swap files, and version control metadata.  A .dirnumignore file can re-include them with "!".
+var DefaultIgnorePatterns = []string{
+	// Windows
+	"Thumbs.db", "ehthumbs.db", "desktop.ini", "$RECYCLE.BIN/",
+	// macOS
+	".DS_Store", "._*", ".AppleDouble/", ".Spotlight-V100/", ".Trashes/", ".fseventsd/", "Icon\r",
+	// Synology and QNAP
+	"@eaDir/", `\#recycle/`, ".@__thumb/",
+	// Editors
+	"*.swp", "*.swo", "*~", ".#*", ".~lock.*#",
+	// Git, whose .git is a file rather than a directory in linked work trees and submodules
+	".git", ".gitignore", ".gitattributes", ".gitmodules", ".gitkeep",
+}
+
+// dirnum's own files, which are always ignored and can never be re-included
+var ownFileRegEx = regexp.MustCompile(`^\.dirnum`)
+
+// ignoreRule is a single pattern from an ignore file
+type ignoreRule struct {
+	base     string // The directory the pattern is relative to
+	pattern  string
+	negate   bool // Re-include names matched by earlier patterns
+	dirOnly  bool // Only match directories
+	anchored bool // Match the path relative to base rather than only the name
+}
+
+// IgnoreMatcher decides which names to ignore.  As with .gitignore, later patterns take precedence over earlier
+// ones, and everything within an ignored directory is ignored.
+type IgnoreMatcher struct {
+	rules []ignoreRule
+}
+
+// LoadIgnore builds the matcher for a directory from the default patterns, the .dirnumignore files in the
+// directory and its parents (outermost first, so that the innermost take precedence) and any extra patterns.
+func LoadIgnore(dir string, extra ...string) (*IgnoreMatcher, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	m := &IgnoreMatcher{}
+	if err := m.AddPatterns(abs, DefaultIgnorePatterns...); err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for d := abs; ; d = filepath.Dir(d) {
+		dirs = append([]string{d}, dirs...)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	for _, d := range dirs {
+		if err := m.AddFile(d); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.AddPatterns(abs, extra...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AddFile adds the patterns of the .dirnumignore file within dir, if there is one
+func (m *IgnoreMatcher) AddFile(dir string) error {
+	p := filepath.Join(dir, IgnoreFileName)
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if err := m.AddPatterns(dir, scanner.Text()); err != nil {
+			return fmt.Errorf("%s:%d: %w", p, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// AddPatterns adds patterns, in the syntax of lines of a .gitignore file, relative to the directory base
+func (m *IgnoreMatcher) AddPatterns(base string, patterns ...string) error {
+	abs, err := filepath.Abs(base)
+	if err != nil {
+		return err
+	}
+	for _, p := range patterns {
+		r, ok, err := parseIgnorePattern(p)
+		if err != nil {
+			return err
+		}
+		if ok {
+			r.base = abs
+			m.rules = append(m.rules, r)
+		}
+	}
+	return nil
+}
+
+// ValidateIgnorePattern reports whether a pattern is valid .gitignore syntax
+func ValidateIgnorePattern(p string) error {
+	_, _, err := parseIgnorePattern(p)
+	return err
+}
+
+// parseIgnorePattern parses one line of an ignore file.  It returns false for blank lines and comments.
+func parseIgnorePattern(line string) (ignoreRule, bool, error) {
+	var r ignoreRule
+	// Trailing spaces are ignored unless escaped
+	p := strings.TrimRight(strings.TrimSuffix(line, "\r"), " ")
+	if strings.HasSuffix(line, `\ `) && !strings.HasSuffix(p, `\ `) {
+		p += " "
+	}
+	if p == "" || strings.HasPrefix(p, "#") {
+		return r, false, nil
+	}
+	if strings.HasPrefix(p, "!") {
+		r.negate = true
+		p = p[1:]
+	} else if strings.HasPrefix(p, `\!`) || strings.HasPrefix(p, `\#`) {
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		r.dirOnly = true
+		p = strings.TrimSuffix(p, "/")
+	}
+	if strings.Contains(p, "/") {
+		r.anchored = true
+		p = strings.TrimPrefix(p, "/")
+	}
+	if p == "" {
+		return r, false, fmt.Errorf("invalid ignore pattern %q", line)
+	}
+	for _, segment := range strings.Split(p, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return r, false, fmt.Errorf("invalid ignore pattern %q: %w", line, err)
+		}
+	}
+	r.pattern = p
+	return r, true, nil
+}
+
+// Match reports whether the file or directory at the given path should be ignored, either itself or because one
+// of the directories containing it is ignored
+func (m *IgnoreMatcher) Match(p string, isDir bool) bool {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return false
+	}
+	if ownFileRegEx.MatchString(filepath.Base(abs)) {
+		return true
+	}
+	for d := filepath.Dir(abs); filepath.Dir(d) != d; d = filepath.Dir(d) {
+		if m.matchOne(d, true) {
+			return true
+		}
+	}
+	return m.matchOne(abs, isDir)
+}
+
+// matchOne applies the rules to a single path, ignoring the directories containing it
+func (m *IgnoreMatcher) matchOne(abs string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.negate != ignored || (r.dirOnly && !isDir) {
+			continue // The rule cannot change the outcome
+		}
+		rel, err := filepath.Rel(r.base, abs)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		var matched bool
+		if r.anchored {
+			matched = matchSegments(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+		} else {
+			matched, _ = path.Match(r.pattern, path.Base(rel))
+		}
+		if matched {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// matchSegments matches a path against a pattern one directory level at a time, where "**" matches any number of
+// levels
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	matched, _ := path.Match(pattern[0], name[0])
+	return matched && matchSegments(pattern[1:], name[1:])
+}
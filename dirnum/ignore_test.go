@@ -0,0 +1,63 @@
+package dirnum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadFileNamesIgnoreFile(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "photos")
+	for _, d := range []string{"@eaDir", "raw", "keep"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, d), 0755))
+	}
+	writeFiles(t, root, map[string]string{IgnoreFileName: "*.txt\n"})
+	writeFiles(t, dir, map[string]string{
+		IgnoreFileName: "# Notes are kept alongside the photos\n/raw/\n!todo.txt\nkeep\n!keep/\n",
+		"0.jpg":        "", "1.jpg~": "", ".DS_Store": "", "desktop.ini": "", ".0.jpg.swp": "",
+		"notes.txt": "", "todo.txt": "", ".dirnum-journal.json": "",
+	})
+
+	files, dirs, err := ReadEntries(dir, "*.jpg~")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0.jpg", "todo.txt"}, files)
+	assert.Equal(t, []string{"keep"}, dirs)
+
+	// Names within ignored directories are ignored, and dirnum's own files cannot be re-included
+	m, err := LoadIgnore(dir, "!.dirnum*")
+	assert.NoError(t, err)
+	assert.True(t, m.Match(filepath.Join(dir, "@eaDir", "0.jpg"), false))
+	assert.True(t, m.Match(filepath.Join(dir, "raw", "0.jpg"), false))
+	assert.False(t, m.Match(filepath.Join(dir, "keep", "raw"), true))
+	assert.True(t, m.Match(filepath.Join(dir, ".dirnum-journal.json"), false))
+
+	// Ignored files are never overwritten by renames
+	assert.Error(t, ApplyRenames(dir, []RenameEntry{{OldName: "0.jpg", NewName: "notes.txt"}}))
+}
+
+func TestIgnorePatterns(t *testing.T) {
+	dir := t.TempDir()
+	m := &IgnoreMatcher{}
+	assert.NoError(t, m.AddPatterns(dir, "a/**/z.jpg", "**/cache", `\#recycle`, `\!bang`, "trailing\\ ", "", "# comment"))
+	for name, ignored := range map[string]bool{
+		"a/z.jpg":       true,
+		"a/b/c/z.jpg":   true,
+		"b/a/z.jpg":     false,
+		"x/y/cache":     true,
+		"#recycle":      true,
+		"!bang":         true,
+		"trailing ":     true,
+		"trailing":      false,
+		"# comment":     false,
+		"x/cache/0.jpg": true,
+	} {
+		assert.Equal(t, ignored, m.Match(filepath.Join(dir, filepath.FromSlash(name)), false), name)
+	}
+
+	assert.Error(t, ValidateIgnorePattern("[a"))
+	assert.Error(t, ValidateIgnorePattern("/"))
+	assert.NoError(t, ValidateIgnorePattern("!*.txt"))
+}
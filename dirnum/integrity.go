@@ -0,0 +1,189 @@
+package dirnum
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Error codes reported by CheckIntegrity
+const (
+	CodeEmptyFile            ErrorCode = "empty-file"            // The file contains no data
+	CodeUnknownFormat        ErrorCode = "unknown-format"        // The contents are not a recognized image format
+	CodeExtensionMismatch    ErrorCode = "extension-mismatch"    // The contents are a different format than the extension claims
+	CodeExtensionUnsupported ErrorCode = "extension-unsupported" // The contents are a format whose extension the schema does not accept
+	CodeTruncated            ErrorCode = "truncated"             // The file ends before the image's end marker
+	CodeUndecodable          ErrorCode = "undecodable"           // The image could not be decoded
+)
+
+// imageFormat describes how to recognize a complete file of a given image format
+type imageFormat struct {
+	extension string // The canonical extension
+	magic     [][]byte
+	trailer   []byte // The bytes a complete file ends with, ignoring trailing padding
+	// appendable formats may be followed by other data, such as the video of a motion photo or the extra images of
+	// an MPF file, so a file which does not end with the trailer is only truncated if decoding runs out of data
+	appendable bool
+}
+
+var imageFormats = []imageFormat{
+	{extension: "jpg", magic: [][]byte{{0xFF, 0xD8, 0xFF}}, trailer: []byte{0xFF, 0xD9}, appendable: true},
+	{extension: "gif", magic: [][]byte{[]byte("GIF87a"), []byte("GIF89a")}, trailer: []byte{0x3B}},
+	{extension: "png", magic: [][]byte{[]byte("\x89PNG\r\n\x1a\n")}, trailer: []byte("IEND\xAE\x42\x60\x82")},
+}
+
+// The number of bytes read from each end of a file to sniff its format
+const sniffLength = 64
+
+// SniffFormat identifies the image format of a file from its leading bytes.  It returns the canonical extension
+// of the format, or "" if the format is not recognized.
+func SniffFormat(head []byte) string {
+	if f := sniff(head); f != nil {
+		return f.extension
+	}
+	return ""
+}
+
+func sniff(head []byte) *imageFormat {
+	for i := range imageFormats {
+		for _, m := range imageFormats[i].magic {
+			if bytes.HasPrefix(head, m) {
+				return &imageFormats[i]
+			}
+		}
+	}
+	return nil
+}
+
+// CheckIntegrity checks file contents using DefaultSchema
+func CheckIntegrity(dir string, files []string, decode bool, workers int) (ValidationErrors, []RenameEntry, error) {
+	return DefaultSchema.CheckIntegrity(dir, files, decode, workers)
+}
+
+// CheckIntegrity compares the contents of every correctly named file with its extension.  Empty, unrecognized,
+// mismatched and truncated files are reported as validation errors, and if decode is set each image is also fully
+// decoded.  It also returns the renames which give mismatched files the extension matching their contents, where
+// the schema accepts that extension.  Files which cannot be read are not validation errors; the first such error is
+// returned.
+func (s *Schema) CheckIntegrity(dir string, files []string, decode bool, workers int) (ValidationErrors, []RenameEntry, error) {
+	var mu sync.Mutex
+	errors := make(ValidationErrors)
+	var renames []RenameEntry
+	var firstErr error
+	forEach(files, workers, func(f string) {
+		name, err := s.Parse(f)
+		if err != nil {
+			return // Reported by ValidateFileNames
+		}
+		e, rename, err := s.checkFile(filepath.Join(dir, f), name, decode)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		if e != nil {
+			errors.add(*e)
+		}
+		if rename != nil {
+			renames = append(renames, *rename)
+		}
+	})
+	sort.Slice(renames, func(i, j int) bool { return renames[i].OldName < renames[j].OldName })
+	return errors, renames, firstErr
+}
+
+// checkFile checks a single file, returning the problem found, if any, and the rename which corrects it, if any
+func (s *Schema) checkFile(path string, name *FileNamePieces, decode bool) (*ValidationError, *RenameEntry, error) {
+	f := name.OriginalName
+	e := &ValidationError{Severity: SeverityError, File: f, Major: name.Major, Minor: name.Minor}
+	fail := func(code ErrorCode, format string, args ...any) (*ValidationError, *RenameEntry, error) {
+		e.Code = code
+		e.Message = fmt.Sprintf(format, args...) + ": " + f
+		return e, nil, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() == 0 {
+		return fail(CodeEmptyFile, "File is empty")
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, nil, err
+	}
+	format := sniff(head[:n])
+	if format == nil {
+		return fail(CodeUnknownFormat, "Contents are not a recognized image format")
+	}
+	if format.extension != name.Extension {
+		e.Code = CodeExtensionMismatch
+		e.Message = fmt.Sprintf("Contents are %s but the extension is %s: %s", format.extension, name.Extension, f)
+		if _, accepted := s.extensions[format.extension]; !accepted {
+			// Renaming the file cannot fix it, so it is reported separately from the mismatches which fix corrects
+			e.Code = CodeExtensionUnsupported
+			e.Message += fmt.Sprintf(" (the schema does not accept %s files)", format.extension)
+			return e, nil, nil
+		}
+		fixed := *name
+		fixed.Extension = format.extension
+		return e, &RenameEntry{OldName: f, NewName: fixed.String()}, nil
+	}
+
+	tail := make([]byte, min(info.Size(), sniffLength))
+	if _, err := file.ReadAt(tail, info.Size()-int64(len(tail))); err != nil {
+		return nil, nil, err
+	}
+	decoded := false
+	var decodeErr error
+	if !bytes.HasSuffix(bytes.TrimRight(tail, "\x00"), format.trailer) {
+		if !format.appendable {
+			return fail(CodeTruncated, "File is truncated")
+		}
+		// The data appended after the image may be of any length, so only decoding finds where the image ends
+		if decodeErr, err = decodeFile(file); err != nil {
+			return nil, nil, err
+		}
+		if errors.Is(decodeErr, io.ErrUnexpectedEOF) || errors.Is(decodeErr, io.EOF) {
+			return fail(CodeTruncated, "File is truncated")
+		}
+		decoded = true
+	}
+
+	if decode && !decoded {
+		if decodeErr, err = decodeFile(file); err != nil {
+			return nil, nil, err
+		}
+	}
+	if decode && decodeErr != nil {
+		return fail(CodeUndecodable, "Image cannot be decoded (%v)", decodeErr)
+	}
+	return nil, nil, nil
+}
+
+// decodeFile decodes the image in a file from its start.  It returns the error decoding the image, if any, separately
+// from an error reading the file.
+func decodeFile(file *os.File) (decodeErr, err error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	_, _, decodeErr = image.Decode(file)
+	return decodeErr, nil
+}
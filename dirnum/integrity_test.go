@@ -0,0 +1,84 @@
+package dirnum
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	saveImage(t, filepath.Join(dir, "0.jpg"), testImage(32, 32, false))
+	saveImage(t, filepath.Join(dir, "1-0-tag.jpg"), testImage(32, 32, false))
+	saveImage(t, filepath.Join(dir, "2.png"), testImage(32, 32, false))
+	assert.NoError(t, os.Rename(filepath.Join(dir, "2.png"), filepath.Join(dir, "1-1.jpg")))
+	writeFiles(t, dir, map[string]string{"3.jpg": "", "4.gif": "hello", "bad name.jpg": ""})
+
+	// A JPEG with its end marker but corrupt image data is only caught by decoding
+	writeFiles(t, dir, map[string]string{"5.jpg": "\xFF\xD8\xFF\xE0garbage\xFF\xD9"})
+
+	// JPEGs may have other data appended, such as the video of a motion photo
+	saveImage(t, filepath.Join(dir, "6.jpg"), testImage(32, 32, false))
+	f, err := os.OpenFile(filepath.Join(dir, "6.jpg"), os.O_APPEND|os.O_WRONLY, 0)
+	assert.NoError(t, err)
+	_, err = f.Write(bytes.Repeat([]byte("ftypmp42"), 1<<14))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	// Cut the end off a JPEG
+	data, err := os.ReadFile(filepath.Join(dir, "1-0-tag.jpg"))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "1-0-tag.jpg"), data[:len(data)/2], 0644))
+
+	files, err := ReadFileNames(dir)
+	assert.NoError(t, err)
+	codes := func(errors ValidationErrors) map[string]ErrorCode {
+		c := make(map[string]ErrorCode)
+		for _, e := range errors.All() {
+			c[e.File] = e.Code
+		}
+		return c
+	}
+
+	errors, renames, err := CheckIntegrity(dir, files, false, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]ErrorCode{
+		"1-0-tag.jpg": CodeTruncated,
+		"1-1.jpg":     CodeExtensionUnsupported,
+		"3.jpg":       CodeEmptyFile,
+		"4.gif":       CodeUnknownFormat,
+	}, codes(errors))
+	// The default schema does not accept png files, so the mismatch cannot be fixed
+	assert.Empty(t, renames)
+	assert.Contains(t, errors.All()[1].Message, "does not accept png")
+	assert.False(t, CodeExtensionUnsupported.Fixable())
+
+	pngs := MustSchema(SchemaConfig{Template: DefaultSchemaConfig.Template, Extensions: []string{"jpg", "gif", "png"}})
+	errors, renames, err = pngs.CheckIntegrity(dir, files, false, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, CodeExtensionMismatch, codes(errors)["1-1.jpg"])
+	assert.Equal(t, []RenameEntry{{OldName: "1-1.jpg", NewName: "1-1.png"}}, renames)
+
+	errors, _, err = CheckIntegrity(dir, files, true, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, CodeUndecodable, codes(errors)["5.jpg"])
+	assert.NotContains(t, codes(errors), "6.jpg")
+}
+
+func TestCheckIntegrityUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	_, _, err := CheckIntegrity(dir, []string{"0.jpg"}, false, 1)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSniffFormat(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, png.Encode(&b, testImage(4, 4, false)))
+	assert.Equal(t, "png", SniffFormat(b.Bytes()))
+	assert.Equal(t, "gif", SniffFormat([]byte("GIF89a...")))
+	assert.Equal(t, "", SniffFormat([]byte("GIF")))
+}
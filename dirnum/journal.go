@@ -0,0 +1,185 @@
+package dirnum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// JournalFileName is the name of the file within a directory recording the renames dirnum has applied
+const JournalFileName = ".dirnum-journal.json"
+
+// JournalEntry records one operation which renamed files
+type JournalEntry struct {
+	Time      time.Time     `json:"time"`
+	Operation string        `json:"operation"` // A short description such as "renumber" or "append 3 onto 2"
+	Renames   []RenameEntry `json:"renames"`
+	// Progress is set while the renames are being applied, so that a run which was interrupted can be undone.  It
+	// is "moving" until every file staged through a temporary name has it, then "placing".
+	Progress string `json:"progress,omitempty"`
+}
+
+// Values of JournalEntry.Progress
+const (
+	progressMoving  = "moving"
+	progressPlacing = "placing"
+)
+
+// Interrupted reports whether the run which applied the renames stopped before finishing them
+func (e *JournalEntry) Interrupted() bool {
+	return e.Progress != ""
+}
+
+// Journal is the history of rename operations applied to a directory, oldest first
+type Journal struct {
+	dir     string
+	Entries []JournalEntry `json:"entries"`
+
+	// Git, if set, moves tracked files with git mv, as GitRepo.ApplyRenames does, so that they keep their history
+	Git *GitRepo `json:"-"`
+}
+
+// ReadJournal loads the journal of a directory.  A directory without a journal has an empty one.
+func ReadJournal(dir string) (*Journal, error) {
+	j := &Journal{dir: dir}
+	data, err := os.ReadFile(filepath.Join(dir, JournalFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("invalid journal %s: %w", filepath.Join(dir, JournalFileName), err)
+	}
+	return j, nil
+}
+
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(j.dir, JournalFileName), append(data, '\n'), 0644)
+}
+
+// Apply applies the renames with ApplyRenames, or with git if Git is set, and records them so that they can be
+// undone.  They are recorded before the first file is moved, so that even a run which is killed part way through
+// can be undone.
+func (j *Journal) Apply(operation string, renames []RenameEntry) error {
+	if len(renames) == 0 {
+		return nil
+	}
+	if last := j.Last(); last != nil && last.Interrupted() {
+		return fmt.Errorf("%s was interrupted; run dirnum undo to restore the names it was changing first", last.Operation)
+	}
+	move, err := j.mover(renames)
+	if err != nil {
+		return err
+	}
+
+	j.Entries = append(j.Entries, JournalEntry{Time: time.Now(), Operation: operation, Renames: renames, Progress: progressMoving})
+	if err := j.save(); err != nil {
+		j.Entries = j.Entries[:len(j.Entries)-1]
+		return err
+	}
+	entry := &j.Entries[len(j.Entries)-1]
+	placing := func(placing bool) error {
+		entry.Progress = progressMoving
+		if placing {
+			entry.Progress = progressPlacing
+		}
+		return j.save()
+	}
+	if err := applyRenames(j.dir, renames, move, placing); err != nil {
+		if errors.Is(err, ErrPartialRename) {
+			return fmt.Errorf("%w; run dirnum undo to restore the original names", err)
+		}
+		// Every move was reversed, so there is nothing to undo
+		j.Entries = j.Entries[:len(j.Entries)-1]
+		if saveErr := j.save(); saveErr != nil {
+			return fmt.Errorf("%w (and the journal could not be updated: %v)", err, saveErr)
+		}
+		return err
+	}
+	entry.Progress = ""
+	if err := j.save(); err != nil {
+		return err
+	}
+	return updateManifest(j.dir, renames)
+}
+
+// Last returns the most recent operation, or nil if the journal is empty
+func (j *Journal) Last() *JournalEntry {
+	if len(j.Entries) == 0 {
+		return nil
+	}
+	return &j.Entries[len(j.Entries)-1]
+}
+
+// Undo reverses the most recent operation and removes it from the journal.  If that operation was interrupted,
+// the files it renamed are given their old names again.  The undo is itself journaled until it finishes, so that it
+// can be undone in turn if it is interrupted.
+func (j *Journal) Undo() (JournalEntry, error) {
+	last := j.Last()
+	if last == nil {
+		return JournalEntry{}, fmt.Errorf("nothing to undo")
+	}
+	entry := *last
+	if entry.Interrupted() {
+		restore := j.restoring(entry)
+		move, err := j.mover(restore)
+		if err == nil {
+			err = applyRenames(j.dir, restore, move, nil)
+		}
+		if err != nil {
+			return entry, fmt.Errorf("cannot undo %s: %w", entry.Operation, err)
+		}
+		j.Entries = j.Entries[:len(j.Entries)-1]
+		return entry, j.save()
+	}
+
+	if err := j.Apply("undo "+entry.Operation, Reverse(entry.Renames)); err != nil {
+		return entry, fmt.Errorf("cannot undo %s: %w", entry.Operation, err)
+	}
+	j.Entries = j.Entries[:len(j.Entries)-2]
+	return entry, j.save()
+}
+
+// restoring returns the renames which give the files of an interrupted operation their old names again.  Each file
+// is still at its old name, at its temporary name or already at its new name, as the progress recorded shows.
+func (j *Journal) restoring(e JournalEntry) []RenameEntry {
+	exists := func(name string) bool {
+		_, err := os.Lstat(filepath.Join(j.dir, name))
+		return err == nil
+	}
+	steps := renameSteps(e.Renames)
+	staged := len(steps) > len(e.Renames)
+
+	var restore []RenameEntry
+	for i, r := range e.Renames {
+		current := r.OldName
+		switch {
+		case staged && exists(steps[i].NewName):
+			current = steps[i].NewName
+		case staged && e.Progress == progressPlacing:
+			current = r.NewName
+		case !staged && !exists(r.OldName):
+			current = r.NewName
+		}
+		if current != r.OldName {
+			restore = append(restore, RenameEntry{OldName: current, NewName: r.OldName})
+		}
+	}
+	return restore
+}
+
+// mover returns the function which moves files within the directory, using git if Git is set
+func (j *Journal) mover(renames []RenameEntry) (func(oldName, newName string) error, error) {
+	if j.Git != nil {
+		return j.Git.mover(j.dir, renames)
+	}
+	return fileMover(j.dir), nil
+}
@@ -0,0 +1,190 @@
+package dirnum
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// LockFileName is the name of the advisory lock file created in a directory while dirnum changes it
+const LockFileName = ".dirnum-lock"
+
+// StaleLockAge is the age after which a lock held by a process on another host is assumed to be abandoned.  Locks
+// held on the same host are stale as soon as their process has exited.
+const StaleLockAge = time.Hour
+
+// ErrLocked is matched, using errors.Is, by the error LockDir returns when another process holds the lock
+var ErrLocked = errors.New("directory is locked")
+
+// LockInfo identifies the process holding a directory's lock
+type LockInfo struct {
+	Owner     string    `json:"owner"` // The user running the process
+	Host      string    `json:"host"`
+	PID       int       `json:"pid"`
+	Operation string    `json:"operation"` // What the process is doing, such as "renumber"
+	Time      time.Time `json:"time"`      // When the lock was acquired
+}
+
+// LockedError reports that a directory is locked by another process
+type LockedError struct {
+	Dir    string
+	Holder LockInfo
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s is busy: %s by %s on %s (pid %d) since %s; if that process is no longer running, delete %s",
+		e.Dir, e.Holder.Operation, e.Holder.Owner, e.Holder.Host, e.Holder.PID, e.Holder.Time.Local().Format("2006-01-02 15:04:05"),
+		filepath.Join(e.Dir, LockFileName))
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// DirLock is a held directory lock
+type DirLock struct {
+	path string
+	info LockInfo
+}
+
+// LockDir acquires the advisory lock of a directory, which every dirnum operation that changes the directory holds
+// so that two runs, possibly on different machines sharing the directory, cannot interleave their renames.  A stale
+// lock, left by a process which has exited on this host or older than StaleLockAge from another host, is replaced.
+// If the lock is held it returns a *LockedError describing the holder.
+func LockDir(dir, operation string) (*DirLock, error) {
+	info := currentLockInfo(operation)
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, LockFileName)
+	for attempt := 0; ; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = f.Write(append(data, '\n'))
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return &DirLock{path: path, info: info}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		holder, err := readLockInfo(path)
+		if errors.Is(err, os.ErrNotExist) && attempt == 0 {
+			continue // Released or replaced since it was found
+		} else if err != nil {
+			return nil, err
+		}
+		if attempt > 0 || !holder.stale(info.Host) {
+			return nil, &LockedError{Dir: dir, Holder: holder}
+		}
+		if err := replaceStaleLock(dir, path, holder); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// replaceStaleLock removes the stale lock at path, held by holder.  Several processes may find the same stale lock,
+// and the first to replace it must not have its new lock removed by the others, so the lock is first moved aside
+// under a unique name and only removed if it is still the one which was found stale.
+func replaceStaleLock(dir, path string, holder LockInfo) error {
+	aside := path + ".stale-" + rand.Text()
+	if err := os.Rename(path, aside); errors.Is(err, os.ErrNotExist) {
+		return nil // Another process moved it first
+	} else if err != nil {
+		return err
+	}
+	moved, err := readLockInfo(aside)
+	if err != nil {
+		return err
+	}
+	if moved.same(holder) {
+		return os.Remove(aside)
+	}
+
+	// Another process replaced the stale lock in the meantime, so its lock is put back, unless yet another process
+	// has since locked the directory
+	if err := os.Link(aside, path); err != nil {
+		return fmt.Errorf("%w (its lock file was moved to %s and could not be put back: %v)", &LockedError{Dir: dir, Holder: moved}, aside, err)
+	}
+	os.Remove(aside)
+	return &LockedError{Dir: dir, Holder: moved}
+}
+
+// Unlock releases the lock, unless another process has since replaced it as stale
+func (l *DirLock) Unlock() error {
+	holder, err := readLockInfo(l.path)
+	if err != nil {
+		return err
+	}
+	if !holder.same(l.info) {
+		return fmt.Errorf("lock %s is no longer held by this process", l.path)
+	}
+	return os.Remove(l.path)
+}
+
+func currentLockInfo(operation string) LockInfo {
+	info := LockInfo{Owner: os.Getenv("USER"), PID: os.Getpid(), Operation: operation, Time: time.Now().UTC()}
+	if u, err := user.Current(); err == nil {
+		info.Owner = u.Username
+	}
+	info.Host, _ = os.Hostname()
+	return info
+}
+
+// readLockInfo reads a lock file.  A lock file which cannot be parsed, for example because its holder is still
+// writing it, is described by its modification time alone.
+func readLockInfo(path string) (LockInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return LockInfo{}, err
+	}
+	var info LockInfo
+	if json.Unmarshal(data, &info) != nil {
+		info = LockInfo{Owner: "unknown", Host: "unknown", Operation: "unknown operation"}
+		if stat, err := os.Stat(path); err == nil {
+			info.Time = stat.ModTime()
+		}
+	}
+	return info, nil
+}
+
+// same reports whether two descriptions of a lock are of the same acquisition by the same process
+func (l LockInfo) same(other LockInfo) bool {
+	return l.Host == other.Host && l.PID == other.PID && l.Time.Equal(other.Time)
+}
+
+// stale reports whether the process holding a lock has gone, as far as can be told from the given host
+func (l LockInfo) stale(host string) bool {
+	if l.Host == host && l.PID > 0 {
+		return !processAlive(l.PID)
+	}
+	return time.Since(l.Time) > StaleLockAge
+}
+
+// processAlive reports whether a process is running on this host
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess only succeeds for running processes on Windows, where signals are unsupported
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,112 @@
+package dirnum
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeLock(t *testing.T, dir string, info LockInfo) {
+	data, err := json.Marshal(info)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, LockFileName), data, 0644))
+}
+
+func TestLockDir(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := LockDir(dir, "renumber")
+	assert.NoError(t, err)
+
+	_, err = LockDir(dir, "append")
+	assert.ErrorIs(t, err, ErrLocked)
+	var locked *LockedError
+	assert.ErrorAs(t, err, &locked)
+	assert.Equal(t, os.Getpid(), locked.Holder.PID)
+	assert.Equal(t, "renumber", locked.Holder.Operation)
+	assert.Contains(t, err.Error(), "is busy: renumber")
+
+	assert.NoError(t, lock.Unlock())
+	lock, err = LockDir(dir, "append")
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Unlock())
+	names, err := readAllNames(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestStaleLocks(t *testing.T) {
+	dir := t.TempDir()
+	host, _ := os.Hostname()
+
+	// A lock left by a process on this host which has exited is replaced
+	if _, err := exec.LookPath("true"); err == nil {
+		cmd := exec.Command("true")
+		assert.NoError(t, cmd.Run())
+		writeLock(t, dir, LockInfo{Host: host, PID: cmd.Process.Pid, Operation: "renumber", Time: time.Now()})
+		lock, err := LockDir(dir, "fix")
+		assert.NoError(t, err)
+		assert.NoError(t, lock.Unlock())
+	}
+
+	// Locks from other hosts are only replaced once they are old
+	writeLock(t, dir, LockInfo{Host: "elsewhere", PID: 1, Operation: "renumber", Time: time.Now().Add(-time.Minute)})
+	_, err := LockDir(dir, "fix")
+	assert.ErrorIs(t, err, ErrLocked)
+	writeLock(t, dir, LockInfo{Host: "elsewhere", PID: 1, Operation: "renumber", Time: time.Now().Add(-2 * StaleLockAge)})
+	lock, err := LockDir(dir, "fix")
+	assert.NoError(t, err)
+
+	// A process whose stale lock was replaced does not remove the new one
+	stale := &DirLock{path: filepath.Join(dir, LockFileName), info: LockInfo{Host: "elsewhere", PID: 1}}
+	assert.Error(t, stale.Unlock())
+	assert.NoError(t, lock.Unlock())
+}
+
+func TestConcurrentStaleLockTakeover(t *testing.T) {
+	dir := t.TempDir()
+	for round := 0; round < 20; round++ {
+		writeLock(t, dir, LockInfo{Host: "elsewhere", PID: 1, Operation: "renumber", Time: time.Now().Add(-2 * StaleLockAge)})
+
+		// Every process finds the same stale lock, but only one may replace it
+		var wg sync.WaitGroup
+		locks := make(chan *DirLock, 8)
+		for i := 0; i < cap(locks); i++ {
+			wg.Go(func() {
+				lock, err := LockDir(dir, "fix")
+				if err == nil {
+					locks <- lock
+				} else {
+					assert.ErrorIs(t, err, ErrLocked)
+				}
+			})
+		}
+		wg.Wait()
+		close(locks)
+		assert.Len(t, locks, 1)
+		for lock := range locks {
+			assert.NoError(t, lock.Unlock())
+		}
+		names, err := readAllNames(dir)
+		assert.NoError(t, err)
+		assert.Empty(t, names)
+	}
+
+	// A process which read the stale lock before another replaced it leaves the new lock in place
+	writeLock(t, dir, LockInfo{Host: "elsewhere", PID: 1, Operation: "renumber", Time: time.Now().Add(-2 * StaleLockAge)})
+	path := filepath.Join(dir, LockFileName)
+	stale, err := readLockInfo(path)
+	assert.NoError(t, err)
+	lock, err := LockDir(dir, "fix")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, replaceStaleLock(dir, path, stale), ErrLocked)
+	assert.NoError(t, lock.Unlock())
+	names, err := readAllNames(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+}
@@ -0,0 +1,173 @@
+package dirnum
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestFileName is the name of the checksum manifest within a directory.  It uses the format of sha256sum, so
+// "sha256sum -c .dirnum-sha256" can verify it without dirnum.
+const ManifestFileName = ".dirnum-sha256"
+
+// Error codes reported by VerifyManifest
+const (
+	CodeChecksumMismatch ErrorCode = "checksum-mismatch" // The contents no longer match the recorded checksum
+	CodeMissingFile      ErrorCode = "missing-file"      // A file in the manifest no longer exists
+	CodeNotInManifest    ErrorCode = "not-in-manifest"   // A file is not recorded in the manifest
+)
+
+// Manifest maps from file name, relative to the directory, to the hex-encoded SHA-256 hash of its contents
+type Manifest map[string]string
+
+// GenerateManifest hashes the files in a directory using a pool of workers
+func GenerateManifest(dir string, files []string, workers int) (Manifest, error) {
+	hashes, err := HashFiles(dir, withoutDirectories(dir, files), workers)
+	return Manifest(hashes), err
+}
+
+// ReadManifest loads the manifest of a directory.  It returns an error satisfying errors.Is(err, os.ErrNotExist)
+// if the directory has no manifest.
+func ReadManifest(dir string) (Manifest, error) {
+	path := filepath.Join(dir, ManifestFileName)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := make(Manifest)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" {
+			continue
+		}
+		// Lines are "<hash>  <name>", or "<hash> *<name>" for files hashed in binary mode
+		hash, name, found := strings.Cut(text, " ")
+		if !found || len(hash) != 64 || len(name) < 2 || (name[0] != ' ' && name[0] != '*') {
+			return nil, fmt.Errorf("invalid manifest %s, line %d", path, line)
+		}
+		m[filepath.FromSlash(name[1:])] = hash
+	}
+	return m, scanner.Err()
+}
+
+// WriteManifest saves the manifest of a directory, sorted by file name
+func WriteManifest(dir string, m Manifest) error {
+	names := make([]string, 0, len(m))
+	for n := range m {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, n := range names {
+		fmt.Fprintf(&b, "%s  %s\n", m[n], filepath.ToSlash(n))
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestFileName), []byte(b.String()), 0644)
+}
+
+// Rename updates the manifest to follow renamed files
+func (m Manifest) Rename(renames []RenameEntry) {
+	hashes := make(map[string]string)
+	for _, r := range renames {
+		if h, found := m[r.OldName]; found {
+			hashes[r.NewName] = h
+			delete(m, r.OldName)
+		}
+	}
+	for n, h := range hashes {
+		m[n] = h
+	}
+}
+
+// Update adds the given files which are not yet in the manifest and removes entries whose files no longer exist.
+// The checksums of files already in the manifest are not recomputed, so that corruption is not hidden.
+func (m Manifest) Update(dir string, files []string, workers int) error {
+	var added []string
+	for _, f := range withoutDirectories(dir, files) {
+		if _, found := m[f]; !found {
+			added = append(added, f)
+		}
+	}
+	hashes, err := HashFiles(dir, added, workers)
+	if err != nil {
+		return err
+	}
+	for f, h := range hashes {
+		m[f] = h
+	}
+	for f := range m {
+		if _, err := os.Lstat(filepath.Join(dir, f)); errors.Is(err, os.ErrNotExist) {
+			delete(m, f)
+		}
+	}
+	return nil
+}
+
+// Verify rehashes every file in the manifest and reports files whose contents have changed or which are missing,
+// along with any of the given files which are not in the manifest
+func (m Manifest) Verify(dir string, files []string, workers int) ValidationErrors {
+	result := make(ValidationErrors)
+	report := func(code ErrorCode, severity Severity, f, message string) {
+		result.add(ValidationError{
+			Code:     code,
+			Severity: severity,
+			File:     f,
+			Major:    NoVersion,
+			Minor:    NoVersion,
+			Message:  message + ": " + f,
+		})
+	}
+
+	var present []string
+	for f := range m {
+		if _, err := os.Lstat(filepath.Join(dir, f)); err != nil {
+			report(CodeMissingFile, SeverityError, f, "File in manifest is missing")
+		} else {
+			present = append(present, f)
+		}
+	}
+	hashes, _ := HashFiles(dir, present, workers)
+	for _, f := range present {
+		if h, found := hashes[f]; !found {
+			report(CodeChecksumMismatch, SeverityError, f, "File cannot be read")
+		} else if h != m[f] {
+			report(CodeChecksumMismatch, SeverityError, f, "Checksum does not match manifest")
+		}
+	}
+	for _, f := range withoutDirectories(dir, files) {
+		if _, found := m[f]; !found {
+			report(CodeNotInManifest, SeverityWarning, f, "File is not in manifest")
+		}
+	}
+	return result
+}
+
+// withoutDirectories removes the subdirectories of dir from files, as only files are recorded in the manifest
+func withoutDirectories(dir string, files []string) []string {
+	var result []string
+	for _, f := range files {
+		if info, err := os.Stat(filepath.Join(dir, f)); err != nil || !info.IsDir() {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// updateManifest updates the manifest of a directory, if it has one, to follow renamed files
+func updateManifest(dir string, renames []RenameEntry) error {
+	m, err := ReadManifest(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	m.Rename(renames)
+	return WriteManifest(dir, m)
+}
@@ -0,0 +1,77 @@
+package dirnum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestFollowsRenames(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"0.jpg": "a", "2-0.jpg": "b", "2-1.jpg": "c"})
+	// Subdirectories, such as exported tags, are not recorded
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "tag_x"), 0755))
+	files, err := ReadFileNames(dir)
+	assert.NoError(t, err)
+
+	m, err := GenerateManifest(dir, files, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, WriteManifest(dir, m))
+	assert.Empty(t, m.Verify(dir, files, 2))
+
+	// Renumbering keeps the manifest in step
+	j, err := ReadJournal(dir)
+	assert.NoError(t, err)
+	assert.NoError(t, j.Apply("renumber", ComputeRenames(files, []int{1})))
+	files, err = ReadFileNames(dir)
+	assert.NoError(t, err)
+	m, err = ReadManifest(dir)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"0.jpg", "1-0.jpg", "1-1.jpg"}, keys(m))
+	assert.Empty(t, m.Verify(dir, files, 2))
+
+	// Corruption, deletion and new files are all reported
+	writeFiles(t, dir, map[string]string{"1-0.jpg": "x", "2.jpg": "d"})
+	assert.NoError(t, os.Remove(filepath.Join(dir, "1-1.jpg")))
+	files, err = ReadFileNames(dir)
+	assert.NoError(t, err)
+	codes := make(map[string]ErrorCode)
+	for _, e := range m.Verify(dir, files, 2).All() {
+		codes[e.File] = e.Code
+	}
+	assert.Equal(t, map[string]ErrorCode{
+		"1-0.jpg": CodeChecksumMismatch,
+		"1-1.jpg": CodeMissingFile,
+		"2.jpg":   CodeNotInManifest,
+	}, codes)
+
+	// Updating adds new files and drops missing ones, but does not hide corruption
+	assert.NoError(t, m.Update(dir, files, 2))
+	assert.ElementsMatch(t, []string{"0.jpg", "1-0.jpg", "2.jpg"}, keys(m))
+	assert.Len(t, m.Verify(dir, files, 2), 1)
+}
+
+func TestReadManifestSha256sumFormat(t *testing.T) {
+	dir := t.TempDir()
+	hash := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	writeFiles(t, dir, map[string]string{
+		ManifestFileName: hash + "  0-foo bar.jpg\n" + hash + " *1.jpg\n",
+	})
+	m, err := ReadManifest(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, Manifest{"0-foo bar.jpg": hash, "1.jpg": hash}, m)
+
+	writeFiles(t, dir, map[string]string{ManifestFileName: "nonsense\n"})
+	_, err = ReadManifest(dir)
+	assert.Error(t, err)
+}
+
+func keys(m Manifest) []string {
+	var k []string
+	for f := range m {
+		k = append(k, f)
+	}
+	return k
+}
@@ -0,0 +1,34 @@
+package dirnum
+
+import "fmt"
+
+// ComputeMigration plans the migration of file names from DefaultSchema to another schema
+func ComputeMigration(files []string, to *Schema) ([]RenameEntry, []error) {
+	return DefaultSchema.ComputeMigration(files, to)
+}
+
+// ComputeMigration plans the renames which rewrite the names of files in the layout of another schema, such as one
+// with a different descriptor style.  Files which do not match this schema are left alone.  Files whose names
+// cannot be written in the other schema, such as those with a descriptor starting with a digit when migrating to
+// DescriptorPlain, are also left alone and reported as errors.
+func (s *Schema) ComputeMigration(files []string, to *Schema) ([]RenameEntry, []error) {
+	var renames []RenameEntry
+	var errs []error
+	for _, f := range files {
+		name, err := s.Parse(f)
+		if err != nil {
+			continue
+		}
+		newName := to.Format(name)
+		if newName == f {
+			continue
+		}
+		migrated, err := to.Parse(newName)
+		if err != nil || migrated.Descriptor != name.Descriptor || compareVersions(migrated.Versions(), name.Versions()) != 0 {
+			errs = append(errs, fmt.Errorf("cannot migrate %s: %s would not be read back as the same name", f, newName))
+			continue
+		}
+		renames = append(renames, RenameEntry{OldName: f, NewName: newName})
+	}
+	return renames, errs
+}
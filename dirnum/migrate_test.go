@@ -0,0 +1,63 @@
+package dirnum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func styledSchema(style DescriptorStyle) *Schema {
+	c := DefaultSchemaConfig
+	c.DescriptorStyle = style
+	return MustSchema(c)
+}
+
+func TestDelimitedDescriptors(t *testing.T) {
+	brackets := styledSchema(DescriptorBrackets)
+	f, err := brackets.Parse("1-0 [2019, beach].jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 0}, f.Versions())
+	assert.Equal(t, []string{"2019", "beach"}, f.Tags())
+	assert.Equal(t, "1-0 [2019, beach].jpg", f.String())
+	_, err = brackets.Parse("0001-0-beach.jpg")
+	assert.Error(t, err)
+	_, err = brackets.Parse("0001 [ beach].jpg")
+	assert.Error(t, err)
+
+	dashes := styledSchema(DescriptorDashes)
+	f, err = dashes.Parse("0001--2019.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, NoVersion, f.Minor)
+	assert.Equal(t, "2019", f.Descriptor)
+	f, err = dashes.Parse("0001-2-3--42.gif")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, f.Versions())
+	assert.Equal(t, "42", f.Descriptor)
+
+	stats := brackets.ComputeStats([]string{"1 [2019].jpg", "2-0 [2019, beach].jpg", "2-1.jpg"})
+	SortStatsAlphabetical(stats)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, "2019", stats[0].Tag)
+	assert.Equal(t, []int{1, 2}, stats[0].Majors())
+
+	_, err = NewSchema(SchemaConfig{Template: DefaultSchemaConfig.Template, Extensions: []string{"jpg"}, DescriptorStyle: "quoted"})
+	assert.Error(t, err)
+	_, err = NewSchema(SchemaConfig{Template: DefaultSchemaConfig.Template, Extensions: []string{"jpg"},
+		DescriptorStyle: DescriptorBrackets, DescriptorChars: `\p{L}\]`})
+	assert.Error(t, err)
+}
+
+func TestComputeMigration(t *testing.T) {
+	files := []string{"0-beach.jpg", "1-0.jpg", "1-1-Paris, café.jpg", "notes.txt"}
+	renames, errs := ComputeMigration(files, styledSchema(DescriptorBrackets))
+	assert.Empty(t, errs)
+	assert.Equal(t, []RenameEntry{
+		{OldName: "0-beach.jpg", NewName: "0 [beach].jpg"},
+		{OldName: "1-1-Paris, café.jpg", NewName: "1-1 [Paris, café].jpg"},
+	}, renames)
+
+	// Descriptors which start with a digit cannot be written in the plain style
+	renames, errs = styledSchema(DescriptorDashes).ComputeMigration([]string{"0--2019.jpg", "1--beach.jpg"}, DefaultSchema)
+	assert.Equal(t, []RenameEntry{{OldName: "1--beach.jpg", NewName: "1-beach.jpg"}}, renames)
+	assert.Len(t, errs, 1)
+}
@@ -0,0 +1,329 @@
+package dirnum
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// RenameEntry is a single file rename within a directory
+type RenameEntry struct {
+	OldName string `json:"oldName"`
+	NewName string `json:"newName"`
+}
+
+// ComputeRenames determines the renames needed using DefaultSchema
+func ComputeRenames(fileNames []string, unused []int) []RenameEntry {
+	return DefaultSchema.ComputeRenames(fileNames, unused)
+}
+
+// ComputeRenames determines the renames needed to fill the gaps in the major numbering, as reported by
+// ValidateFileNames, and to renumber the versions within each group contiguously from zero at every level.  Reserved
+// numbers are never filled, and the files of frozen groups are left as they are.
+func (s *Schema) ComputeRenames(fileNames []string, unused []int) []RenameEntry {
+	parsed := s.ParseFileNames(fileNames)
+	renumberVersions(parsed)
+	unused = slices.DeleteFunc(slices.Clone(unused), s.IsReserved)
+	files := slices.DeleteFunc(slices.Clone(parsed), func(f *FileNamePieces) bool { return s.IsFrozen(f.Major) })
+	if len(files) == 0 {
+		return changedNames(files)
+	}
+
+	// Fill in gaps in major numbers.
+	// Determine what major version to use to begin filling holes
+	majorIdx := len(files) - 1 // Skip the first value since the largest major version will fit in that slot
+	for unusedIdx := 0; unusedIdx < len(unused); unusedIdx++ {
+		// If the "hole" is larger than what we would fill it with, we've completed the sequence
+		if majorIdx <= 0 || unused[unusedIdx] > files[majorIdx].Major {
+			break
+		}
+
+		// Ensure that we're on the first file with this major version
+		for ; majorIdx > 0 && files[majorIdx-1].Major == files[majorIdx].Major; majorIdx-- {
+		}
+
+		majorIdx--
+	}
+	// Undo our last shift - it's the one that pushed us too far
+	if majorIdx < len(files)-1 {
+		majorIdx++
+	}
+	// Rename the files to fill in the holes
+	for _, u := range unused {
+		if majorIdx >= len(files) || u > files[majorIdx].Major {
+			break
+		}
+
+		for oldMajor := files[majorIdx].Major; majorIdx < len(files) && files[majorIdx].Major == oldMajor; majorIdx++ {
+			files[majorIdx].Major = u
+		}
+	}
+
+	return changedNames(files)
+}
+
+// ComputeFixes determines the renames needed using DefaultSchema
+func ComputeFixes(fileNames []string) []RenameEntry {
+	return DefaultSchema.ComputeFixes(fileNames)
+}
+
+// ComputeFixes determines the renames needed to tidy file names without moving any major group: minor versions are
+// renumbered contiguously from zero, version numbers are padded consistently and extensions are normalized.  The
+// files of frozen groups are left as they are.
+func (s *Schema) ComputeFixes(fileNames []string) []RenameEntry {
+	return s.ComputeRenames(fileNames, nil)
+}
+
+// Computes the number of digits required at each level of the version, keyed by the version of the group (""
+// for the major numbers).  That is, if the largest major version is 100, 3 digits are required to represent the
+// major version (in base 10).  For each group below, the digits required are the most used by any file in the
+// group.  Thus, "0-0", "0-1", "1-0", "1-1", ..., "1-10" would return ["": 1, "0": 1, "1": 2] because the minor
+// versions of major version 0 require one digit while those of major version 1 require 2.
+//
+// We intentionally ignore the edge case where filling the gaps will reduce the number of digits required - if so, the extra digit
+// will likely be required soon enough.  If it's particularly important, running the tool a second time will remove the extra digit.
+func computeDigitCounts(files PFnpSlice) map[string]int {
+	counts := make(map[string]int)
+	for _, f := range files {
+		versions, digits := f.Versions(), f.Digits()
+		for i := range versions {
+			key := FormatVersion(versions[:i])
+			counts[key] = max(counts[key], digits[i])
+		}
+	}
+	return counts
+}
+
+// Pads the version components of every file to the digits computed by computeDigitCounts
+func padVersions(files PFnpSlice) {
+	counts := computeDigitCounts(files)
+	for _, f := range files {
+		versions := f.Versions()
+		digits := make([]int, len(versions))
+		for i := range versions {
+			digits[i] = counts[FormatVersion(versions[:i])]
+		}
+		f.SetVersions(versions, digits)
+	}
+}
+
+// Renumbers the versions below the major version of all files.  Within each group the components are numbered
+// contiguously from zero in their current order.  If only one file exists in a group, its component is cleared, as
+// the minor version of a lone file in a major group is; a group holding a single sub-group is merged into it.
+func renumberVersions(files PFnpSlice) {
+	sort.Stable(files)
+	for start := 0; start < len(files); {
+		end := start + 1
+		for ; end < len(files) && files[end].Major == files[start].Major; end++ {
+		}
+		assignVersions(files[start:end], 1, []int{files[start].Major}, []int{0})
+		start = end
+	}
+	padVersions(files)
+}
+
+// Numbers the sub-groups of files, which share the version prefix, by their component at depth.  levels records
+// the depth each component of prefix was taken from, so that the new components keep the padding of the old.
+func assignVersions(files PFnpSlice, depth int, prefix, levels []int) {
+	groups := groupByVersion(files, depth)
+	if len(groups) == 1 && len(files) > 1 {
+		assignVersions(files, depth+1, prefix, levels)
+		return
+	}
+	for i, g := range groups {
+		p, l := prefix, levels
+		if len(groups) > 1 {
+			p, l = append(slices.Clone(prefix), i), append(slices.Clone(levels), depth)
+		}
+		if len(g) > 1 {
+			assignVersions(g, depth+1, p, l)
+			continue
+		}
+		old := g[0].Digits()
+		digits := make([]int, len(p))
+		for i, level := range l {
+			if level < len(old) {
+				digits[i] = old[level]
+			}
+		}
+		g[0].SetVersions(p, digits)
+	}
+}
+
+// Splits files, sorted by version, into the groups sharing their component at depth.  Files without the component
+// are in groups of their own, as are files which would only share a group because they have the same version.
+func groupByVersion(files PFnpSlice, depth int) []PFnpSlice {
+	var groups []PFnpSlice
+	for _, f := range files {
+		v := f.Versions()
+		if n := len(groups); n > 0 && depth < len(v) {
+			if last := groups[n-1][0].Versions(); depth < len(last) && last[depth] == v[depth] {
+				groups[n-1] = append(groups[n-1], f)
+				continue
+			}
+		}
+		groups = append(groups, PFnpSlice{f})
+	}
+
+	split := make([]PFnpSlice, 0, len(groups))
+	for _, g := range groups {
+		deeper := slices.ContainsFunc(g, func(f *FileNamePieces) bool { return len(f.Versions()) > depth+1 })
+		if len(g) == 1 || deeper {
+			split = append(split, g)
+			continue
+		}
+		for _, f := range g {
+			split = append(split, PFnpSlice{f})
+		}
+	}
+	return split
+}
+
+// Gives a file new leading version components in place of the first replaced components of its version.  The new
+// components are padded to their natural width and the rest keep their padding.
+func moveVersion(f *FileNamePieces, replaced int, leading []int) {
+	versions, digits := slices.Clone(leading), make([]int, len(leading))
+	for i, v := range leading {
+		digits[i] = len(strconv.Itoa(v))
+	}
+	f.SetVersions(append(versions, f.Versions()[replaced:]...), append(digits, f.Digits()[replaced:]...))
+}
+
+// Determine any files whose names changed
+func changedNames(files PFnpSlice) []RenameEntry {
+	renames := make([]RenameEntry, 0)
+	for _, f := range files {
+		old := f.OriginalName
+		new := f.String()
+		if old != new {
+			renames = append(renames, RenameEntry{OldName: old, NewName: new})
+		}
+	}
+	return renames
+}
+
+// ComputeAppend determines the renames needed using DefaultSchema
+func ComputeAppend(fileNames []string, from, onto int) []RenameEntry {
+	return DefaultSchema.ComputeAppend(fileNames, from, onto)
+}
+
+// Computes the renames needed to append one major group to another.  If they cannot be appended, for example because
+// either is frozen, no renames are returned; ComputeAppendVersion reports why.
+func (s *Schema) ComputeAppend(fileNames []string, from, onto int) []RenameEntry {
+	renames, err := s.ComputeAppendVersion(fileNames, []int{from}, []int{onto})
+	if err != nil {
+		return []RenameEntry{}
+	}
+	return renames
+}
+
+// ComputeAppendVersion determines the renames needed using DefaultSchema
+func ComputeAppendVersion(fileNames []string, from, onto []int) ([]RenameEntry, error) {
+	return DefaultSchema.ComputeAppendVersion(fileNames, from, onto)
+}
+
+// ComputeAppendVersion computes the renames needed to append the group with the version from to the group with the
+// version onto, at any level: appending 12-3 onto 12-1 makes the sub-groups and files of 12-3 the next ones of
+// 12-1, keeping any levels below them.  If onto has files but no numbered sub-groups, its files are numbered first.
+// Neither may be within a frozen group.
+func (s *Schema) ComputeAppendVersion(fileNames []string, from, onto []int) ([]RenameEntry, error) {
+	if len(from) == 0 || len(onto) == 0 {
+		return nil, fmt.Errorf("cannot append without a version to append from and onto")
+	}
+	if hasVersionPrefix(from, onto) || hasVersionPrefix(onto, from) {
+		return nil, fmt.Errorf("cannot append %s onto %s: one contains the other", FormatVersion(from), FormatVersion(onto))
+	}
+	if err := s.checkNotFrozen(from, onto); err != nil {
+		return nil, fmt.Errorf("cannot append %s onto %s: %w", FormatVersion(from), FormatVersion(onto), err)
+	}
+	files := s.ParseFileNames(fileNames)
+
+	var fromFiles, ontoFiles PFnpSlice
+	for _, f := range files {
+		if v := f.Versions(); hasVersionPrefix(v, from) {
+			fromFiles = append(fromFiles, f)
+		} else if hasVersionPrefix(v, onto) {
+			ontoFiles = append(ontoFiles, f)
+		}
+	}
+	if len(fromFiles) == 0 {
+		return []RenameEntry{}, nil
+	}
+
+	// Find the next free number below onto, numbering files with no version below onto if there are no others
+	next := 0
+	for _, f := range ontoFiles {
+		if v := f.Versions(); len(v) > len(onto) {
+			next = max(next, v[len(onto)]+1)
+		}
+	}
+	if next == 0 {
+		for _, f := range ontoFiles {
+			moveVersion(f, len(onto), append(slices.Clone(onto), next))
+			next++
+		}
+	}
+
+	// Append the sub-groups of from, in order
+	for _, g := range groupByVersion(fromFiles, len(from)) {
+		for _, f := range g {
+			moveVersion(f, min(len(from)+1, len(f.Versions())), append(slices.Clone(onto), next))
+		}
+		next++
+	}
+
+	// Restrict renames to the files now within onto
+	padVersions(files)
+	return changedNames(append(ontoFiles, fromFiles...)), nil
+}
+
+// ComputeSplit determines the renames needed using DefaultSchema
+func ComputeSplit(fileNames []string, from, to []int) ([]RenameEntry, error) {
+	return DefaultSchema.ComputeSplit(fileNames, from, to)
+}
+
+// ComputeSplit computes the renames needed to move the group with the version from, at any level, to the unused
+// version to, keeping any levels below it: splitting 12-3 to 40 turns 12-3-0 and 12-3-1 into 40-0 and 40-1.  The
+// group it leaves may then need renumbering.  Neither may be within a frozen group.
+func (s *Schema) ComputeSplit(fileNames []string, from, to []int) ([]RenameEntry, error) {
+	if len(from) == 0 || len(to) == 0 {
+		return nil, fmt.Errorf("cannot split without a version to split from and to")
+	}
+	if hasVersionPrefix(from, to) || hasVersionPrefix(to, from) {
+		return nil, fmt.Errorf("cannot split %s to %s: one contains the other", FormatVersion(from), FormatVersion(to))
+	}
+	if err := s.checkNotFrozen(from, to); err != nil {
+		return nil, fmt.Errorf("cannot split %s to %s: %w", FormatVersion(from), FormatVersion(to), err)
+	}
+	files := s.ParseFileNames(fileNames)
+
+	var moved PFnpSlice
+	for _, f := range files {
+		v := f.Versions()
+		if hasVersionPrefix(v, to) {
+			return nil, fmt.Errorf("cannot split %s to %s: %s is already numbered %s", FormatVersion(from), FormatVersion(to), f.OriginalName, FormatVersion(to))
+		}
+		if hasVersionPrefix(v, from) {
+			moved = append(moved, f)
+		}
+	}
+	if len(moved) == 0 {
+		return nil, fmt.Errorf("no files are numbered %s", FormatVersion(from))
+	}
+	for _, f := range moved {
+		moveVersion(f, len(from), to)
+	}
+	padVersions(files)
+	return changedNames(moved), nil
+}
+
+// checkNotFrozen reports an error if any of the versions is within a frozen major group
+func (s *Schema) checkNotFrozen(versions ...[]int) error {
+	for _, v := range versions {
+		if s.IsFrozen(v[0]) {
+			return fmt.Errorf("major group %d is frozen", v[0])
+		}
+	}
+	return nil
+}
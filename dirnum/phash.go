@@ -0,0 +1,232 @@
+package dirnum
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif" // Register the decoders for the supported image formats
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// HashAlgorithm selects the perceptual hash used to compare images
+type HashAlgorithm string
+
+const (
+	AverageHashAlgorithm    HashAlgorithm = "ahash" // Each bit records whether a cell is brighter than the mean
+	DifferenceHashAlgorithm HashAlgorithm = "dhash" // Each bit records whether a cell is brighter than its right neighbour
+)
+
+// imageExtensions are the extensions of files which can be decoded for perceptual hashing
+var imageExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".gif": true, ".png": true}
+
+// ImageHash holds the perceptual hashes of one image
+type ImageHash struct {
+	File  string `json:"file"` // Relative to the directory which was hashed
+	AHash uint64 `json:"ahash"`
+	DHash uint64 `json:"dhash"`
+}
+
+// Hash returns the hash computed by the given algorithm
+func (h ImageHash) Hash(algorithm HashAlgorithm) uint64 {
+	if algorithm == AverageHashAlgorithm {
+		return h.AHash
+	}
+	return h.DHash
+}
+
+// SimilarPair is two images whose perceptual hashes are within the similarity threshold
+type SimilarPair struct {
+	A        string `json:"a"`
+	B        string `json:"b"`
+	Distance int    `json:"distance"` // The number of differing hash bits; 0 means visually identical
+}
+
+// ParseHashAlgorithm converts the name of a hash algorithm into a HashAlgorithm
+func ParseHashAlgorithm(s string) (HashAlgorithm, error) {
+	switch a := HashAlgorithm(s); a {
+	case AverageHashAlgorithm, DifferenceHashAlgorithm:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown hash algorithm %q: expected %q or %q", s, AverageHashAlgorithm, DifferenceHashAlgorithm)
+}
+
+// grayscaleGrid shrinks an image to a w×h grid of average luminance values
+func grayscaleGrid(img image.Image, w, h int) []float64 {
+	b := img.Bounds()
+	sums := make([]float64, w*h)
+	counts := make([]int, w*h)
+	ycbcr, isYCbCr := img.(*image.YCbCr)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := (y - b.Min.Y) * h / b.Dy()
+		for x := b.Min.X; x < b.Max.X; x++ {
+			cell := row*w + (x-b.Min.X)*w/b.Dx()
+			if isYCbCr {
+				// JPEGs decode to YCbCr, whose Y channel is already the luminance
+				sums[cell] += float64(ycbcr.Y[ycbcr.YOffset(x, y)])
+			} else {
+				r, g, bl, _ := img.At(x, y).RGBA()
+				sums[cell] += (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)) / 257
+			}
+			counts[cell]++
+		}
+	}
+	for i := range sums {
+		if counts[i] > 0 {
+			sums[i] /= float64(counts[i])
+		}
+	}
+	return sums
+}
+
+// AverageHash computes a 64-bit hash of an image by shrinking it to 8×8 and recording which cells are brighter
+// than the mean
+func AverageHash(img image.Image) uint64 {
+	grid := grayscaleGrid(img, 8, 8)
+	mean := 0.0
+	for _, v := range grid {
+		mean += v
+	}
+	mean /= float64(len(grid))
+	var hash uint64
+	for i, v := range grid {
+		if v > mean {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// DifferenceHash computes a 64-bit hash of an image by shrinking it to 9×8 and recording which cells are brighter
+// than their right-hand neighbour.  It is more robust than AverageHash to changes in brightness and contrast.
+func DifferenceHash(img image.Image) uint64 {
+	grid := grayscaleGrid(img, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if grid[y*9+x] > grid[y*9+x+1] {
+				hash |= 1 << uint(y*8+x)
+			}
+		}
+	}
+	return hash
+}
+
+// HammingDistance counts the bits which differ between two hashes
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// HashImage decodes an image file and computes its perceptual hashes
+func HashImage(path string) (ImageHash, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ImageHash{}, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return ImageHash{}, fmt.Errorf("cannot decode %s: %w", path, err)
+	}
+	if img.Bounds().Empty() {
+		return ImageHash{}, fmt.Errorf("cannot hash %s: image is empty", path)
+	}
+	return ImageHash{AHash: AverageHash(img), DHash: DifferenceHash(img)}, nil
+}
+
+// HashImages computes the perceptual hashes of images within a directory using a pool of workers.  Images which
+// cannot be decoded are skipped, and the errors describing them returned alongside the hashes.
+func HashImages(dir string, files []string, workers int) ([]ImageHash, []error) {
+	var mu sync.Mutex
+	var hashes []ImageHash
+	var errs []error
+	forEach(files, workers, func(f string) {
+		h, err := HashImage(filepath.Join(dir, f))
+		h.File = f
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			hashes = append(hashes, h)
+		}
+	})
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i].File < hashes[j].File })
+	return hashes, errs
+}
+
+// ImageFiles filters a list of file names to those which can be decoded as images
+func ImageFiles(files []string) []string {
+	var images []string
+	for _, f := range files {
+		if imageExtensions[strings.ToLower(filepath.Ext(f))] {
+			images = append(images, f)
+		}
+	}
+	return images
+}
+
+// ImageFilesInTree lists the image files below root, as paths relative to root.  Ignored files and directories,
+// including those named by .dirnumignore files within the tree, are skipped.
+func ImageFilesInTree(root string, ignore ...string) ([]string, error) {
+	m, err := LoadIgnore(root, ignore...)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root {
+			if m.Match(path, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return m.AddFile(path)
+			}
+		}
+		if d.Type().IsRegular() && imageExtensions[strings.ToLower(filepath.Ext(path))] {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// FindSimilar compares every pair of images, using a pool of workers, and returns those whose hashes differ by at
+// most threshold bits, closest first
+func FindSimilar(hashes []ImageHash, algorithm HashAlgorithm, threshold, workers int) []SimilarPair {
+	// Each worker compares one image with all of those after it, and its pairs are kept in image order so that the
+	// result does not depend on scheduling
+	rows := make([][]SimilarPair, len(hashes))
+	indices := make([]int, len(hashes))
+	for i := range indices {
+		indices[i] = i
+	}
+	forEach(indices, workers, func(i int) {
+		a := hashes[i].Hash(algorithm)
+		for j := i + 1; j < len(hashes); j++ {
+			if d := HammingDistance(a, hashes[j].Hash(algorithm)); d <= threshold {
+				rows[i] = append(rows[i], SimilarPair{A: hashes[i].File, B: hashes[j].File, Distance: d})
+			}
+		}
+	})
+	pairs := slices.Concat(rows...)
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Distance < pairs[j].Distance })
+	return pairs
+}
@@ -0,0 +1,85 @@
+package dirnum
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testImage draws a w×h image of a diagonal gradient, optionally mirrored
+func testImage(w, h int, mirrored bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := (x*255/w + y*255/h) / 2
+			if mirrored {
+				v = 255 - v
+			}
+			img.Set(x, y, color.RGBA{uint8(v), uint8(v / 2), uint8(255 - v), 255})
+		}
+	}
+	return img
+}
+
+func saveImage(t *testing.T, path string, img image.Image) {
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	if filepath.Ext(path) == ".png" {
+		assert.NoError(t, png.Encode(f, img))
+	} else {
+		assert.NoError(t, jpeg.Encode(f, img, &jpeg.Options{Quality: 60}))
+	}
+}
+
+func TestPerceptualHashes(t *testing.T) {
+	original := testImage(320, 240, false)
+	resized := testImage(64, 48, false)
+	different := testImage(320, 240, true)
+
+	for _, hash := range []func(image.Image) uint64{AverageHash, DifferenceHash} {
+		assert.LessOrEqual(t, HammingDistance(hash(original), hash(resized)), 4)
+		assert.Greater(t, HammingDistance(hash(original), hash(different)), 20)
+	}
+}
+
+func TestFindSimilar(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	saveImage(t, filepath.Join(dir, "0.png"), testImage(320, 240, false))
+	saveImage(t, filepath.Join(dir, "sub", "1.jpg"), testImage(160, 120, false))
+	saveImage(t, filepath.Join(dir, "2.png"), testImage(320, 240, true))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "3.jpg"), []byte("not an image"), 0644))
+
+	files, err := ImageFilesInTree(dir)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"0.png", filepath.Join("sub", "1.jpg"), "2.png", "3.jpg"}, files)
+
+	hashes, errs := HashImages(dir, files, 2)
+	assert.Len(t, hashes, 3)
+	assert.Len(t, errs, 1)
+
+	pairs := FindSimilar(hashes, DifferenceHashAlgorithm, 8, 2)
+	assert.Len(t, pairs, 1)
+	assert.Equal(t, "0.png", pairs[0].A)
+	assert.Equal(t, filepath.Join("sub", "1.jpg"), pairs[0].B)
+}
+
+func TestFindSimilarWorkers(t *testing.T) {
+	// The result is the same however many workers compare the images
+	var hashes []ImageHash
+	for i := range 200 {
+		h := uint64(i) * 0x9E3779B97F4A7C15
+		hashes = append(hashes, ImageHash{File: strconv.Itoa(i) + ".jpg", DHash: h >> (i % 7)})
+	}
+	serial := FindSimilar(hashes, DifferenceHashAlgorithm, 20, 1)
+	assert.NotEmpty(t, serial)
+	assert.Equal(t, serial, FindSimilar(hashes, DifferenceHashAlgorithm, 20, 8))
+}
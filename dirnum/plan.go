@@ -0,0 +1,119 @@
+package dirnum
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PlanVersion is the version of the plan file format written by WritePlan
+const PlanVersion = 1
+
+// FileState records a file as it was when a plan was made, so that applying the plan can detect changes since
+type FileState struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	SHA256  string    `json:"sha256,omitempty"` // Optional hex-encoded hash of the contents
+}
+
+// Plan is a rename plan saved to be reviewed, edited and applied later, possibly on another copy of the directory.
+// Renames may be edited by hand, but every file renamed must have its state recorded in Sources.
+type Plan struct {
+	Version   int                  `json:"version"`
+	Operation string               `json:"operation"` // A short description such as "renumber", as in the journal
+	Created   time.Time            `json:"created"`
+	Renames   []RenameEntry        `json:"renames"`
+	Sources   map[string]FileState `json:"sources"` // The state of each file renamed, keyed by its old name
+}
+
+// NewPlan records the state of the files renamed by a plan.  If hash is set, the contents of each are hashed as
+// well, which allows the plan to be applied to another copy of the directory.
+func NewPlan(dir, operation string, renames []RenameEntry, hash bool) (*Plan, error) {
+	p := &Plan{Version: PlanVersion, Operation: operation, Created: time.Now(), Renames: renames,
+		Sources: make(map[string]FileState, len(renames))}
+	for _, r := range renames {
+		state, err := readFileState(filepath.Join(dir, r.OldName), hash)
+		if err != nil {
+			return nil, err
+		}
+		p.Sources[r.OldName] = state
+	}
+	return p, nil
+}
+
+func readFileState(path string, hash bool) (FileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileState{}, err
+	}
+	state := FileState{Size: info.Size(), ModTime: info.ModTime().UTC()}
+	if hash {
+		if state.SHA256, err = HashFile(path); err != nil {
+			return state, err
+		}
+	}
+	return state, nil
+}
+
+// ReadPlan loads a plan previously written by WritePlan
+func ReadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid plan file %s: %w", path, err)
+	}
+	if p.Version != PlanVersion {
+		return nil, fmt.Errorf("plan file %s has unsupported version %d", path, p.Version)
+	}
+	// Edited plans must not reach outside the directory
+	for _, r := range p.Renames {
+		if !filepath.IsLocal(r.OldName) || !filepath.IsLocal(r.NewName) {
+			return nil, fmt.Errorf("invalid plan file %s: cannot rename %s to %s", path, r.OldName, r.NewName)
+		}
+	}
+	return &p, nil
+}
+
+// WritePlan saves a plan as indented JSON, so that it can be reviewed and edited
+func WritePlan(path string, p *Plan) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// CheckDrift reports whether the files a plan renames have changed since it was made.  Each must exist with the
+// recorded size and either the recorded hash or, if no hash was recorded, the recorded modification time.  Checking
+// the hash instead of the time allows the plan to be applied to a copy, such as a clone of a git repository, whose
+// files have different times.
+func (p *Plan) CheckDrift(dir string) error {
+	var drifted []string
+	for _, r := range p.Renames {
+		want, found := p.Sources[r.OldName]
+		if !found {
+			return fmt.Errorf("the plan does not record the state of %s", r.OldName)
+		}
+		got, err := readFileState(filepath.Join(dir, r.OldName), want.SHA256 != "")
+		switch {
+		case err != nil:
+			drifted = append(drifted, r.OldName+" (missing)")
+		case got.Size != want.Size:
+			drifted = append(drifted, r.OldName+" (size changed)")
+		case want.SHA256 != "" && got.SHA256 != want.SHA256:
+			drifted = append(drifted, r.OldName+" (contents changed)")
+		case want.SHA256 == "" && !got.ModTime.Equal(want.ModTime):
+			drifted = append(drifted, r.OldName+" (modified)")
+		}
+	}
+	if len(drifted) > 0 {
+		return fmt.Errorf("the directory has changed since the plan was made: %s", strings.Join(drifted, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package dirnum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanDrift(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"0.jpg": "a", "2.jpg": "b", "3.jpg": "c"})
+	renames := []RenameEntry{{OldName: "2.jpg", NewName: "1.jpg"}, {OldName: "3.jpg", NewName: "2.jpg"}}
+
+	plan, err := NewPlan(dir, "renumber", renames, false)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "plan.json")
+	assert.NoError(t, WritePlan(path, plan))
+	plan, err = ReadPlan(path)
+	assert.NoError(t, err)
+	assert.Equal(t, renames, plan.Renames)
+	assert.NoError(t, plan.CheckDrift(dir))
+
+	// Touching a file is drift unless the plan recorded its contents
+	later := time.Now().Add(time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "3.jpg"), later, later))
+	assert.ErrorContains(t, plan.CheckDrift(dir), "3.jpg (modified)")
+
+	hashed, err := NewPlan(dir, "renumber", renames, true)
+	assert.NoError(t, err)
+	earlier := time.Now().Add(-time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "3.jpg"), earlier, earlier))
+	assert.NoError(t, hashed.CheckDrift(dir))
+	writeFiles(t, dir, map[string]string{"3.jpg": "d"})
+	assert.ErrorContains(t, hashed.CheckDrift(dir), "3.jpg (contents changed)")
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "2.jpg")))
+	assert.ErrorContains(t, hashed.CheckDrift(dir), "2.jpg (missing)")
+
+	// Renames added by hand must have a recorded state
+	hashed.Renames = append(hashed.Renames, RenameEntry{OldName: "0.jpg", NewName: "5.jpg"})
+	assert.ErrorContains(t, hashed.CheckDrift(dir), "does not record the state of 0.jpg")
+}
+
+func TestReadPlanRejectsEscapes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"version": 1, "renames": [{"oldName": "0.jpg", "newName": "../0.jpg"}]}`), 0644))
+	_, err := ReadPlan(path)
+	assert.Error(t, err)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"version": 2}`), 0644))
+	_, err = ReadPlan(path)
+	assert.ErrorContains(t, err, "unsupported version")
+}
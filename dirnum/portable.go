@@ -0,0 +1,44 @@
+package dirnum
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// CodeUnsafeName is reported for file names which cannot be stored on every common filesystem
+const CodeUnsafeName ErrorCode = "unsafe-name" // The name contains characters or forms some filesystems reject
+
+// unsafeCharacters are rejected in file names by Windows, and '/' by every filesystem.  ':' is also rejected by
+// classic macOS.
+const unsafeCharacters = `<>:"/\|?*`
+
+// Names reserved by Windows regardless of their extension
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// isUnsafeRune reports whether a character is rejected in file names by a common filesystem
+func isUnsafeRune(r rune) bool {
+	return strings.ContainsRune(unsafeCharacters, r) || unicode.IsControl(r) || r == unicode.ReplacementChar
+}
+
+// CheckPortableName reports why a file name cannot be stored on every common filesystem: it contains characters
+// which Windows or macOS reject, is not valid UTF-8, ends with a space or dot, or is a name Windows reserves.
+func CheckPortableName(name string) error {
+	for _, r := range name {
+		if isUnsafeRune(r) {
+			return fmt.Errorf("contains the character %q, which is unsafe on common filesystems", r)
+		}
+	}
+	if strings.HasSuffix(name, " ") || strings.HasSuffix(name, ".") {
+		return fmt.Errorf("ends with a space or dot, which Windows removes")
+	}
+	base, _, _ := strings.Cut(name, ".")
+	if reservedNames[strings.ToUpper(strings.TrimSpace(base))] {
+		return fmt.Errorf("is a name reserved by Windows")
+	}
+	return nil
+}
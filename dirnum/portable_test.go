@@ -0,0 +1,49 @@
+package dirnum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnicodeDescriptors(t *testing.T) {
+	valid := []string{"0-café.jpg", "1-Zürich, Genève.jpg", "2-東京.jpg", "3-rock&roll.jpg", "4-Ελλάδα 2019.jpg"}
+	errors, _ := ValidateFileNames(valid, false, false)
+	assert.Empty(t, errors)
+
+	f, err := ParseFileName("1-Zürich, Genève.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Zürich", "Genève"}, f.Tags())
+
+	// Descriptors still start with a letter, so they cannot be mistaken for a minor version
+	_, err = ParseFileName("0-١٢.jpg")
+	assert.Error(t, err)
+
+	// The allowed characters are configurable
+	s := MustSchema(SchemaConfig{Template: DefaultSchemaConfig.Template, Extensions: []string{"jpg"}, DescriptorChars: `A-Za-z0-9 +`})
+	_, err = s.Parse("0-café.jpg")
+	assert.Error(t, err)
+	_, err = s.Parse("0-C++.jpg")
+	assert.NoError(t, err)
+}
+
+func TestUnsafeDescriptorChars(t *testing.T) {
+	for _, chars := range []string{`\p{L}:`, `^a`, `\x00-\x7f`, `a-z?`, `\p{Nope}`} {
+		_, err := NewSchema(SchemaConfig{Template: DefaultSchemaConfig.Template, Extensions: []string{"jpg"}, DescriptorChars: chars})
+		assert.Error(t, err, chars)
+	}
+}
+
+func TestCheckPortableName(t *testing.T) {
+	assert.NoError(t, CheckPortableName("0012-東京.jpg"))
+	for _, name := range []string{"0:1.jpg", "a?.jpg", "tab\t.jpg", "0.jpg.", "0.jpg ", "con.jpg", "LPT1", "bad\xff.jpg"} {
+		assert.Error(t, CheckPortableName(name), name)
+	}
+
+	// A custom pattern may accept unsafe names, but validation still rejects them
+	s := MustSchema(SchemaConfig{Template: DefaultSchemaConfig.Template, Extensions: []string{"jpg"},
+		Pattern: `^(?P<major>[0-9]+)(?:-(?P<descriptor>.+))?\.(?P<extension>jpg)$`})
+	errors, _ := s.ValidateFileNames([]string{"0-what?.jpg", "1-ok.jpg"}, true, false)
+	assert.Len(t, errors.All(), 1)
+	assert.Equal(t, CodeUnsafeName, errors["0-what?.jpg"][0].Code)
+}
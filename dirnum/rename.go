@@ -0,0 +1,249 @@
+package dirnum
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tempPrefix starts the temporary names used while applying renames.  It begins with ".dirnum" so that these names
+// are never renamed themselves; files left behind by an interrupted run are reported by FindInterruptedRenames.
+const tempPrefix = ".dirnum-tmp-"
+
+// ErrPartialRename is matched, using errors.Is, by the error returned when renames failed part way through and the
+// renames already made could not all be reversed
+var ErrPartialRename = errors.New("renames were only partly applied")
+
+// CheckRenames verifies that applying the renames to a directory containing the existing files would not lose any
+// file: every source must exist and be renamed only once, and no two files may end up with the same name.
+func CheckRenames(existing []string, renames []RenameEntry) error {
+	present := make(map[string]bool)
+	for _, f := range existing {
+		present[f] = true
+	}
+
+	sources := make(map[string]bool)
+	for _, r := range renames {
+		if !present[r.OldName] {
+			return fmt.Errorf("cannot rename %s: file does not exist", r.OldName)
+		}
+		if sources[r.OldName] {
+			return fmt.Errorf("cannot rename %s more than once", r.OldName)
+		}
+		sources[r.OldName] = true
+	}
+
+	targets := make(map[string]string)
+	for _, r := range renames {
+		if other, found := targets[r.NewName]; found {
+			return fmt.Errorf("cannot rename both %s and %s to %s", other, r.OldName, r.NewName)
+		}
+		targets[r.NewName] = r.OldName
+		if present[r.NewName] && !sources[r.NewName] {
+			return fmt.Errorf("cannot rename %s to %s: file already exists", r.OldName, r.NewName)
+		}
+	}
+	return nil
+}
+
+// ApplyRenames renames files within a directory.  The renames are checked with CheckRenames first, and when one
+// file is renamed to the old name of another the files are moved through temporary names so that the order of the
+// renames does not matter.  Renames may move files into subdirectories, which are created if necessary.  If a move
+// fails, the moves already made are reversed.  If the directory has a checksum manifest, it is updated to follow
+// the renamed files.
+func ApplyRenames(dir string, renames []RenameEntry) error {
+	if err := applyRenames(dir, renames, fileMover(dir), nil); err != nil {
+		return err
+	}
+	return updateManifest(dir, renames)
+}
+
+// fileMover returns a function which renames files within dir
+func fileMover(dir string) func(oldName, newName string) error {
+	return func(oldName, newName string) error {
+		return RenameFile(oldName, newName, dir)
+	}
+}
+
+// applyRenames checks and applies renames, moving each file with move, which is given names within dir.  If the
+// renames are staged through temporary names, placing is called with true once every file has its temporary name
+// and before any is moved on, and with false if the files are moved back to their temporary names while reversing
+// a failed rename.  If a move fails, the moves already made are reversed; if that fails too, the error matches
+// ErrPartialRename.
+func applyRenames(dir string, renames []RenameEntry, move func(oldName, newName string) error, placing func(bool) error) error {
+	// Ignored files are included so that a rename cannot overwrite them
+	existing, err := readAllNames(dir)
+	if err != nil {
+		return err
+	}
+	// Files in subdirectories, such as those being restored from the trash, are not listed
+	for _, r := range renames {
+		if filepath.Dir(r.OldName) != "." {
+			if _, err := os.Lstat(filepath.Join(dir, r.OldName)); err == nil {
+				existing = append(existing, r.OldName)
+			}
+		}
+	}
+	if err := CheckRenames(existing, renames); err != nil {
+		return err
+	}
+	// Subdirectories are never renamed, so that tag folders and group directories keep their contents in place
+	for _, r := range renames {
+		if info, err := os.Lstat(filepath.Join(dir, r.OldName)); err == nil && info.IsDir() {
+			return fmt.Errorf("cannot rename %s: it is a directory", r.OldName)
+		}
+	}
+	for _, r := range renames {
+		if sub := filepath.Dir(r.NewName); sub != "." {
+			if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+				return err
+			}
+		}
+	}
+
+	steps := renameSteps(renames)
+	staged := len(steps) > len(renames)
+	for i, step := range steps {
+		var err error
+		if staged && i == len(renames) && placing != nil {
+			err = placing(true)
+		}
+		if err == nil && strings.HasPrefix(step.NewName, tempPrefix) {
+			if _, statErr := os.Lstat(filepath.Join(dir, step.NewName)); statErr == nil {
+				err = fmt.Errorf("cannot stage %s: %s already exists", step.OldName, step.NewName)
+			}
+		}
+		if err == nil {
+			err = move(step.OldName, step.NewName)
+		}
+		if err != nil {
+			return rollBack(steps[:i], len(renames), move, placing, err)
+		}
+	}
+	return nil
+}
+
+// rollBack reverses the moves made before a rename failed with err.  staging is the number of moves to temporary
+// names which begin staged renames.
+func rollBack(done []RenameEntry, staging int, move func(oldName, newName string) error, placing func(bool) error, err error) error {
+	for i := len(done) - 1; i >= 0; i-- {
+		if len(done) > staging && i == staging-1 && placing != nil {
+			if placeErr := placing(false); placeErr != nil {
+				return fmt.Errorf("%w (%w: %v)", err, ErrPartialRename, placeErr)
+			}
+		}
+		if undoErr := move(done[i].NewName, done[i].OldName); undoErr != nil {
+			return fmt.Errorf("%w (%w: cannot move %s back to %s: %v)", err, ErrPartialRename, done[i].NewName, done[i].OldName, undoErr)
+		}
+	}
+	return err
+}
+
+// CodeInterruptedRename is reported by FindInterruptedRenames
+const CodeInterruptedRename ErrorCode = "interrupted-rename" // A file was left with a temporary name by a rename which did not finish
+
+// FindInterruptedRenames reports the files in a directory which were left with temporary names by renames which
+// were interrupted.  Such names are otherwise ignored, so the files would silently drop out of validation.
+func FindInterruptedRenames(dir string) (ValidationErrors, error) {
+	names, err := readAllNames(dir)
+	if err != nil {
+		return nil, err
+	}
+	errors := make(ValidationErrors)
+	for _, n := range names {
+		if strings.HasPrefix(n, tempPrefix) {
+			errors.add(ValidationError{
+				Code:     CodeInterruptedRename,
+				Severity: SeverityError,
+				File:     n,
+				Major:    NoVersion,
+				Minor:    NoVersion,
+				Message:  "File left with a temporary name by an interrupted rename; run dirnum undo to restore it: " + n,
+			})
+		}
+	}
+	return errors, nil
+}
+
+// renameSteps returns the individual moves which apply renames, in order.  When one file is renamed to the old name
+// of another, every file is first moved to a temporary name so that the order of the renames does not matter.
+func renameSteps(renames []RenameEntry) []RenameEntry {
+	sources := make(map[string]bool)
+	for _, r := range renames {
+		sources[r.OldName] = true
+	}
+	staged := false
+	for _, r := range renames {
+		if sources[r.NewName] {
+			staged = true
+			break
+		}
+	}
+	if !staged {
+		return renames
+	}
+
+	steps := make([]RenameEntry, 0, 2*len(renames))
+	temps := make([]string, len(renames))
+	for i, r := range renames {
+		temps[i] = tempPrefix + strconv.Itoa(i) + "-" + filepath.Base(r.NewName)
+		steps = append(steps, RenameEntry{OldName: r.OldName, NewName: temps[i]})
+	}
+	for i, r := range renames {
+		steps = append(steps, RenameEntry{OldName: temps[i], NewName: r.NewName})
+	}
+	return steps
+}
+
+// Reverse returns the renames which undo the given renames
+func Reverse(renames []RenameEntry) []RenameEntry {
+	reversed := make([]RenameEntry, len(renames))
+	for i, r := range renames {
+		reversed[len(renames)-1-i] = RenameEntry{OldName: r.NewName, NewName: r.OldName}
+	}
+	return reversed
+}
+
+// RenamedNames returns the file names which result from applying the renames to a list of file names
+func RenamedNames(files []string, renames []RenameEntry) []string {
+	newNames := make(map[string]string)
+	for _, r := range renames {
+		newNames[r.OldName] = r.NewName
+	}
+	result := make([]string, len(files))
+	for i, f := range files {
+		if n, found := newNames[f]; found {
+			f = n
+		}
+		result[i] = f
+	}
+	return result
+}
+
+// ComposeRenames combines two plans into one, where second was computed from the names produced by first
+func ComposeRenames(first, second []RenameEntry) []RenameEntry {
+	combined := make([]RenameEntry, len(first))
+	copy(combined, first)
+	byNewName := make(map[string]int)
+	for i, r := range combined {
+		byNewName[r.NewName] = i
+	}
+	for _, r := range second {
+		if i, found := byNewName[r.OldName]; found {
+			combined[i].NewName = r.NewName
+		} else {
+			combined = append(combined, r)
+		}
+	}
+
+	result := combined[:0]
+	for _, r := range combined {
+		if r.OldName != r.NewName {
+			result = append(result, r)
+		}
+	}
+	return result
+}
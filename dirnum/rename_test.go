@@ -0,0 +1,135 @@
+package dirnum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+}
+
+func readFiles(t *testing.T, dir string) map[string]string {
+	names, err := ReadFileNames(dir)
+	assert.NoError(t, err)
+	files := make(map[string]string)
+	for _, n := range names {
+		contents, err := os.ReadFile(filepath.Join(dir, n))
+		assert.NoError(t, err)
+		files[n] = string(contents)
+	}
+	return files
+}
+
+func TestCheckRenames(t *testing.T) {
+	existing := []string{"0.jpg", "1.jpg", "2.jpg"}
+	assert.NoError(t, CheckRenames(existing, []RenameEntry{{"0.jpg", "1.jpg"}, {"1.jpg", "0.jpg"}}))
+	assert.Error(t, CheckRenames(existing, []RenameEntry{{"3.jpg", "4.jpg"}}))
+	assert.Error(t, CheckRenames(existing, []RenameEntry{{"0.jpg", "2.jpg"}}))
+	assert.Error(t, CheckRenames(existing, []RenameEntry{{"0.jpg", "3.jpg"}, {"1.jpg", "3.jpg"}}))
+	assert.Error(t, CheckRenames(existing, []RenameEntry{{"0.jpg", "3.jpg"}, {"0.jpg", "4.jpg"}}))
+}
+
+func TestComposeRenames(t *testing.T) {
+	first := []RenameEntry{{"5.jpeg", "5.png"}, {"7.jpg", "7.png"}}
+	files := RenamedNames([]string{"0.jpg", "5.jpeg", "7.jpg"}, first)
+	assert.Equal(t, []string{"0.jpg", "5.png", "7.png"}, files)
+
+	second := []RenameEntry{{"5.png", "1.png"}, {"7.png", "7.jpg"}, {"0.jpg", "0-0.jpg"}}
+	assert.Equal(t, []RenameEntry{{"5.jpeg", "1.png"}, {"0.jpg", "0-0.jpg"}}, ComposeRenames(first, second))
+}
+
+func TestApplyRenamesSwap(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"0.jpg": "a", "1.jpg": "b", "2.jpg": "c"})
+
+	assert.NoError(t, ApplyRenames(dir, []RenameEntry{{"0.jpg", "1.jpg"}, {"1.jpg", "0.jpg"}, {"2.jpg", "3.jpg"}}))
+	assert.Equal(t, map[string]string{"0.jpg": "b", "1.jpg": "a", "3.jpg": "c"}, readFiles(t, dir))
+
+	// A conflicting plan leaves the directory untouched
+	assert.Error(t, ApplyRenames(dir, []RenameEntry{{"0.jpg", "4.jpg"}, {"3.jpg", "1.jpg"}}))
+	assert.Equal(t, map[string]string{"0.jpg": "b", "1.jpg": "a", "3.jpg": "c"}, readFiles(t, dir))
+}
+
+func TestJournalUndo(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"0.jpg": "a", "2-0.jpg": "b", "2-1.jpg": "c"})
+
+	j, err := ReadJournal(dir)
+	assert.NoError(t, err)
+	assert.Nil(t, j.Last())
+	assert.NoError(t, j.Apply("renumber", ComputeRenames([]string{"0.jpg", "2-0.jpg", "2-1.jpg"}, []int{1})))
+	assert.Equal(t, map[string]string{"0.jpg": "a", "1-0.jpg": "b", "1-1.jpg": "c"}, readFiles(t, dir))
+
+	// The journal is persisted in the directory
+	j, err = ReadJournal(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "renumber", j.Last().Operation)
+
+	entry, err := j.Undo()
+	assert.NoError(t, err)
+	assert.Equal(t, "renumber", entry.Operation)
+	assert.Equal(t, map[string]string{"0.jpg": "a", "2-0.jpg": "b", "2-1.jpg": "c"}, readFiles(t, dir))
+	assert.Nil(t, j.Last())
+
+	_, err = j.Undo()
+	assert.Error(t, err)
+}
+
+func TestApplyRenamesRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"0.jpg": "a", "1.jpg": "b", "2.jpg": "c"})
+	renames := []RenameEntry{{"0.jpg", "1.jpg"}, {"1.jpg", "0.jpg"}, {"2.jpg", "3.jpg"}}
+
+	// Fail each of the moves in turn: the moves made before it are reversed
+	for fail := 0; fail < len(renameSteps(renames)); fail++ {
+		moves := 0
+		move := func(oldName, newName string) error {
+			if moves++; moves == fail+1 {
+				return os.ErrPermission
+			}
+			return RenameFile(oldName, newName, dir)
+		}
+		err := applyRenames(dir, renames, move, nil)
+		assert.ErrorIs(t, err, os.ErrPermission)
+		assert.NotErrorIs(t, err, ErrPartialRename)
+		assert.Equal(t, map[string]string{"0.jpg": "a", "1.jpg": "b", "2.jpg": "c"}, readFiles(t, dir))
+		interrupted, err := FindInterruptedRenames(dir)
+		assert.NoError(t, err)
+		assert.Empty(t, interrupted)
+	}
+}
+
+func TestJournalUndoInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	renames := []RenameEntry{{"0.jpg", "1.jpg"}, {"1.jpg", "0.jpg"}, {"2.jpg", "3.jpg"}}
+	// The run was killed after moving every file to its temporary name and then the first to its new name
+	writeFiles(t, dir, map[string]string{"1.jpg": "a", tempPrefix + "1-0.jpg": "b", tempPrefix + "2-3.jpg": "c"})
+	j, err := ReadJournal(dir)
+	assert.NoError(t, err)
+	j.Entries = append(j.Entries, JournalEntry{Operation: "renumber", Renames: renames, Progress: progressPlacing})
+	assert.NoError(t, j.save())
+
+	interrupted, err := FindInterruptedRenames(dir)
+	assert.NoError(t, err)
+	assert.Len(t, interrupted, 2)
+	assert.Equal(t, CodeInterruptedRename, interrupted.All()[0].Code)
+
+	j, err = ReadJournal(dir)
+	assert.NoError(t, err)
+	assert.True(t, j.Last().Interrupted())
+	assert.ErrorContains(t, j.Apply("fix", []RenameEntry{{"1.jpg", "5.jpg"}}), "interrupted")
+
+	_, err = j.Undo()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"0.jpg": "a", "1.jpg": "b", "2.jpg": "c"}, readFiles(t, dir))
+	assert.Nil(t, j.Last())
+	interrupted, err = FindInterruptedRenames(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, interrupted)
+}
@@ -0,0 +1,497 @@
+package dirnum
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SchemaConfig describes how file names are laid out.  The template is made up of literal text and the
+// placeholders {major}, {minor}, {descriptor} and {extension}.  Square brackets mark an optional section which is
+// omitted when the placeholder within it is empty; {minor} and {descriptor} must appear in optional sections and
+// {major} and {extension} must not.  For example:
+//
+//	{major}[-{minor}][-{descriptor}].{extension}     0001-2-tag.jpg (the default)
+//	IMG-{major}[_{minor}][ {descriptor}].{extension}  IMG-0001_02 tag.jpg
+//	p{major}[.{minor}].{extension}                   p001.03.jpg
+//
+// The text before {minor} in its optional section also separates any deeper version components, so the default
+// template accepts 0012-03-01.jpg.  File names are formatted with the template and, unless a custom Pattern is
+// given, parsed with a regular expression derived from it.
+//
+// A schema also records the major numbers which renumbering must leave alone: reserved numbers are set aside for
+// groups yet to be added, and frozen groups are referred to from elsewhere and keep their numbers.
+type SchemaConfig struct {
+	Template string `json:"template"`
+	// Pattern optionally overrides the regular expression used to parse file names.  It must contain the named
+	// groups "major" and "extension" and may contain "minor" and "descriptor".  Each run of digits in the "minor"
+	// group is a version component.
+	Pattern string `json:"pattern,omitempty"`
+	// Extensions lists the accepted extensions, without the leading dot
+	Extensions []string `json:"extensions"`
+	// ExtensionAliases maps alternate spellings of an extension to the accepted extension they are renamed to
+	ExtensionAliases map[string]string `json:"extensionAliases,omitempty"`
+	// DescriptorChars are the characters allowed in a descriptor after its first, which must be a letter, written as
+	// the contents of a regular expression character class.  Empty means DefaultDescriptorChars.
+	DescriptorChars string `json:"descriptorChars,omitempty"`
+	// DescriptorStyle selects how the descriptor is delimited.  Empty means DescriptorPlain.
+	DescriptorStyle DescriptorStyle `json:"descriptorStyle,omitempty"`
+	// Reserved lists major numbers which are not in use but are not gaps: they are never reported as skipped and
+	// never filled by ComputeRenames
+	Reserved []int `json:"reserved,omitempty"`
+	// Frozen lists major groups which are never renumbered, moved or appended to, nor have their files renamed by
+	// ComputeRenames or ComputeFixes
+	Frozen []int `json:"frozen,omitempty"`
+}
+
+// DescriptorStyle selects how a descriptor is separated from the version numbers before it
+type DescriptorStyle string
+
+const (
+	// DescriptorPlain uses the template's own text before {descriptor}.  The descriptor must start with a letter so
+	// that it cannot be mistaken for a version number: 0001-0-beach.jpg
+	DescriptorPlain DescriptorStyle = "plain"
+	// DescriptorDashes puts "--" before the descriptor, which may then start with a digit: 0001-0--2019, beach.jpg
+	DescriptorDashes DescriptorStyle = "dashes"
+	// DescriptorBrackets puts the descriptor in square brackets after a space: 0001-0 [2019, beach].jpg
+	DescriptorBrackets DescriptorStyle = "brackets"
+)
+
+// descriptorDelimiters maps each delimited style to the text before and after the descriptor
+var descriptorDelimiters = map[DescriptorStyle][2]string{
+	DescriptorDashes:   {"--", ""},
+	DescriptorBrackets: {" [", "]"},
+}
+
+// ParseDescriptorStyle converts a style name into a DescriptorStyle
+func ParseDescriptorStyle(s string) (DescriptorStyle, error) {
+	style := DescriptorStyle(s)
+	if _, delimited := descriptorDelimiters[style]; !delimited && style != DescriptorPlain {
+		return "", fmt.Errorf("unknown descriptor style %q", s)
+	}
+	return style, nil
+}
+
+// DefaultDescriptorChars allows letters and digits of any script, with their combining marks, and _ ' , & and space
+const DefaultDescriptorChars = `\p{L}\p{M}\p{N}_' ,&`
+
+// DefaultSchemaConfig is the traditional dirnum naming scheme
+var DefaultSchemaConfig = SchemaConfig{
+	Template:         "{major}[-{minor}][-{descriptor}].{extension}",
+	Extensions:       []string{"jpg", "gif"},
+	ExtensionAliases: map[string]string{"jpeg": "jpg"},
+	DescriptorChars:  DefaultDescriptorChars,
+	DescriptorStyle:  DescriptorPlain,
+}
+
+// DefaultSchema is the compiled DefaultSchemaConfig.  The package-level functions use it.
+var DefaultSchema = MustSchema(DefaultSchemaConfig)
+
+// Placeholder names within a schema template
+const (
+	fieldMajor      = "major"
+	fieldMinor      = "minor"
+	fieldDescriptor = "descriptor"
+	fieldExtension  = "extension"
+)
+
+// templatePart is a piece of a parsed template: literal text, a placeholder, or an optional section
+type templatePart struct {
+	literal  string
+	field    string
+	optional []templatePart
+}
+
+// Schema is a compiled SchemaConfig which parses and formats file names
+type Schema struct {
+	config     SchemaConfig
+	template   []templatePart
+	re         *regexp.Regexp
+	extensions map[string]string // Maps every accepted spelling of an extension to its canonical form
+	separator  string            // Separates the version components below the major number
+	descriptor string            // The regular expression matching a descriptor
+}
+
+// NewSchema compiles a schema, reporting any problems with its template or pattern
+func NewSchema(c SchemaConfig) (*Schema, error) {
+	s := &Schema{config: c, extensions: make(map[string]string)}
+	if len(c.Extensions) == 0 {
+		return nil, fmt.Errorf("schema must accept at least one extension")
+	}
+	for _, e := range c.Extensions {
+		s.extensions[e] = e
+	}
+	for alias, e := range c.ExtensionAliases {
+		s.extensions[alias] = e
+	}
+
+	style := c.DescriptorStyle
+	if style == "" {
+		style = DescriptorPlain
+	}
+	if _, err := ParseDescriptorStyle(string(style)); err != nil {
+		return nil, err
+	}
+	for _, n := range slices.Concat(c.Reserved, c.Frozen) {
+		if n < 0 {
+			return nil, fmt.Errorf("reserved and frozen major numbers must not be negative: %d", n)
+		}
+	}
+	for _, n := range c.Reserved {
+		if slices.Contains(c.Frozen, n) {
+			return nil, fmt.Errorf("major number %d cannot be both reserved and frozen", n)
+		}
+	}
+	parts, err := parseTemplate(c.Template)
+	if err != nil {
+		return nil, err
+	}
+	parts = delimitDescriptor(parts, style)
+	s.template = parts
+	s.separator = levelSeparator(parts)
+	if s.descriptor, err = descriptorRegex(c.DescriptorChars, style); err != nil {
+		return nil, err
+	}
+
+	pattern := c.Pattern
+	if pattern == "" {
+		pattern = `^` + s.templateRegex(parts) + `$`
+	}
+	s.re, err = regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema pattern: %w", err)
+	}
+	for _, field := range []string{fieldMajor, fieldExtension} {
+		if s.re.SubexpIndex(field) < 0 {
+			return nil, fmt.Errorf("schema pattern must contain the named group %q", field)
+		}
+	}
+	return s, nil
+}
+
+// MustSchema is like NewSchema but panics if the schema is invalid
+func MustSchema(c SchemaConfig) *Schema {
+	s, err := NewSchema(c)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Config returns the configuration the schema was compiled from
+func (s *Schema) Config() SchemaConfig {
+	return s.config
+}
+
+// WithDescriptorStyle returns a schema which is the same except for its descriptor style
+func (s *Schema) WithDescriptorStyle(style DescriptorStyle) (*Schema, error) {
+	c := s.config
+	c.DescriptorStyle = style
+	return NewSchema(c)
+}
+
+// IsReserved reports whether a major number is reserved
+func (s *Schema) IsReserved(major int) bool {
+	return slices.Contains(s.config.Reserved, major)
+}
+
+// IsFrozen reports whether a major group is frozen
+func (s *Schema) IsFrozen(major int) bool {
+	return slices.Contains(s.config.Frozen, major)
+}
+
+// parseTemplate splits a template into literal text, placeholders and optional sections
+func parseTemplate(template string) ([]templatePart, error) {
+	parts, rest, err := parseTemplateParts(template, false)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unbalanced ']' in schema template %q", template)
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range parts {
+		if p.field == fieldMinor || p.field == fieldDescriptor {
+			return nil, fmt.Errorf("{%s} must be in an optional section in schema template %q", p.field, template)
+		}
+		for _, o := range p.optional {
+			if o.field == fieldMajor || o.field == fieldExtension {
+				return nil, fmt.Errorf("{%s} must not be in an optional section in schema template %q", o.field, template)
+			}
+			if o.field != "" {
+				if seen[o.field] {
+					return nil, fmt.Errorf("{%s} appears more than once in schema template %q", o.field, template)
+				}
+				seen[o.field] = true
+			}
+		}
+		if p.field != "" {
+			if seen[p.field] {
+				return nil, fmt.Errorf("{%s} appears more than once in schema template %q", p.field, template)
+			}
+			seen[p.field] = true
+		}
+	}
+	for _, field := range []string{fieldMajor, fieldExtension} {
+		if !seen[field] {
+			return nil, fmt.Errorf("schema template %q must contain {%s}", template, field)
+		}
+	}
+	return parts, nil
+}
+
+// parseTemplateParts parses until the end of the template or, within an optional section, the closing bracket.
+// It returns the parts and the unparsed remainder of the template.
+func parseTemplateParts(t string, inOptional bool) ([]templatePart, string, error) {
+	var parts []templatePart
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, templatePart{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for len(t) > 0 {
+		switch t[0] {
+		case '{':
+			end := strings.IndexByte(t, '}')
+			if end < 0 {
+				return nil, "", fmt.Errorf("unterminated placeholder in schema template")
+			}
+			field := t[1:end]
+			switch field {
+			case fieldMajor, fieldMinor, fieldDescriptor, fieldExtension:
+			default:
+				return nil, "", fmt.Errorf("unknown placeholder {%s} in schema template", field)
+			}
+			flush()
+			parts = append(parts, templatePart{field: field})
+			t = t[end+1:]
+		case '[':
+			if inOptional {
+				return nil, "", fmt.Errorf("optional sections may not be nested in schema template")
+			}
+			flush()
+			optional, rest, err := parseTemplateParts(t[1:], true)
+			if err != nil {
+				return nil, "", err
+			}
+			if !strings.HasPrefix(rest, "]") {
+				return nil, "", fmt.Errorf("unterminated optional section in schema template")
+			}
+			fields := 0
+			for _, o := range optional {
+				if o.field != "" {
+					fields++
+				}
+			}
+			if fields != 1 {
+				return nil, "", fmt.Errorf("each optional section in a schema template must contain exactly one placeholder")
+			}
+			parts = append(parts, templatePart{optional: optional})
+			t = rest[1:]
+		case ']':
+			flush()
+			return parts, t, nil
+		default:
+			literal.WriteByte(t[0])
+			t = t[1:]
+		}
+	}
+	flush()
+	return parts, "", nil
+}
+
+// delimitDescriptor replaces the text around {descriptor} in its optional section with the delimiters of a
+// delimited style
+func delimitDescriptor(parts []templatePart, style DescriptorStyle) []templatePart {
+	delimiters, delimited := descriptorDelimiters[style]
+	if !delimited {
+		return parts
+	}
+	result := make([]templatePart, len(parts))
+	for i, p := range parts {
+		result[i] = p
+		if hasField(p.optional, fieldDescriptor) {
+			result[i].optional = []templatePart{{literal: delimiters[0]}, {field: fieldDescriptor}}
+			if delimiters[1] != "" {
+				result[i].optional = append(result[i].optional, templatePart{literal: delimiters[1]})
+			}
+		}
+	}
+	return result
+}
+
+// descriptorRegex builds the regular expression matching a descriptor made of the given characters.  It rejects
+// character sets which allow characters that are unsafe in file names on common filesystems, or which would allow
+// a delimited descriptor to contain its closing delimiter.
+func descriptorRegex(chars string, style DescriptorStyle) (string, error) {
+	if chars == "" {
+		chars = DefaultDescriptorChars
+	}
+	class := `[` + chars + `]`
+	re, err := regexp.Compile(`^` + class + `$`)
+	if err != nil {
+		return "", fmt.Errorf("invalid descriptor characters %q: %w", chars, err)
+	}
+	for _, r := range unsafeCharacters + "\x00\t\n\x1f\x7f" {
+		if re.MatchString(string(r)) {
+			return "", fmt.Errorf("descriptor characters %q allow %q, which is unsafe in file names", chars, r)
+		}
+	}
+	delimiters, delimited := descriptorDelimiters[style]
+	if !delimited {
+		return `\p{L}` + class + `+`, nil
+	}
+	if delimiters[1] != "" && re.MatchString(delimiters[1]) {
+		return "", fmt.Errorf("descriptor characters %q allow %q, which ends a %s descriptor", chars, delimiters[1], style)
+	}
+	// The delimiter makes a descriptor unambiguous, so it may start with a digit but not with a space or punctuation
+	return `[\p{L}\p{N}]` + class + `*`, nil
+}
+
+// levelSeparator returns the literal text before {minor} in its optional section, or "-" if there is none
+func levelSeparator(parts []templatePart) string {
+	for _, p := range parts {
+		for i, o := range p.optional {
+			if o.field == fieldMinor && i > 0 && p.optional[i-1].literal != "" {
+				return p.optional[i-1].literal
+			}
+		}
+	}
+	return "-"
+}
+
+// templateRegex builds the regular expression which matches the template parts
+func (s *Schema) templateRegex(parts []templatePart) string {
+	var b strings.Builder
+	for _, p := range parts {
+		switch {
+		case p.optional != nil:
+			b.WriteString(`(?:` + s.templateRegex(p.optional) + `)?`)
+		case p.field == fieldMajor:
+			b.WriteString(`(?P<major>[0-9]+)`)
+		case p.field == fieldMinor:
+			b.WriteString(`(?P<minor>[0-9]+(?:` + regexp.QuoteMeta(s.separator) + `[0-9]+)*)`)
+		case p.field == fieldDescriptor:
+			b.WriteString(`(?P<descriptor>` + s.descriptor + `)`)
+		case p.field == fieldExtension:
+			exts := make([]string, 0, len(s.extensions))
+			for e := range s.extensions {
+				exts = append(exts, regexp.QuoteMeta(e))
+			}
+			sort.Strings(exts)
+			b.WriteString(`(?P<extension>` + strings.Join(exts, "|") + `)`)
+		default:
+			b.WriteString(regexp.QuoteMeta(p.literal))
+		}
+	}
+	return b.String()
+}
+
+// Parse splits a file name into its version numbers, descriptor and extension
+func (s *Schema) Parse(f string) (*FileNamePieces, error) {
+	tokens := s.re.FindStringSubmatch(f)
+	if tokens == nil {
+		return nil, fmt.Errorf("bad filename: %s", f)
+	}
+	group := func(name string) string {
+		if i := s.re.SubexpIndex(name); i >= 0 {
+			return tokens[i]
+		}
+		return ""
+	}
+
+	majorStr := group(fieldMajor)
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid major version \"%s\": %s", majorStr, f)
+	}
+	versions := []int{major}
+	minorStr := group(fieldMinor)
+	for _, v := range digitsRegEx.FindAllString(minorStr, -1) {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid minor version \"%s\": %s", minorStr, f)
+		}
+		versions = append(versions, n)
+	}
+	digits := make([]int, len(versions))
+	for i, v := range versions {
+		digits[i] = len(strconv.Itoa(v))
+	}
+	extension, found := s.extensions[group(fieldExtension)]
+	if !found {
+		return nil, fmt.Errorf("bad filename: %s", f)
+	}
+
+	name := &FileNamePieces{
+		Descriptor:   group(fieldDescriptor),
+		Extension:    extension,
+		OriginalName: f,
+		schema:       s.ref(),
+	}
+	name.SetVersions(versions, digits)
+	return name, nil
+}
+
+var digitsRegEx = regexp.MustCompile(`[0-9]+`)
+
+// Format produces the file name for the given pieces
+func (s *Schema) Format(f *FileNamePieces) string {
+	var b strings.Builder
+	b.Grow(len(f.OriginalName))
+	s.formatParts(&b, s.template, f)
+	return b.String()
+}
+
+func (s *Schema) formatParts(b *strings.Builder, parts []templatePart, f *FileNamePieces) {
+	for _, p := range parts {
+		switch {
+		case p.optional != nil:
+			if hasField(p.optional, fieldMinor) && f.Minor == NoVersion {
+				continue
+			}
+			if hasField(p.optional, fieldDescriptor) && len(f.Descriptor) == 0 {
+				continue
+			}
+			s.formatParts(b, p.optional, f)
+		case p.field == fieldMajor:
+			fmt.Fprintf(b, "%0*d", f.MajorDigits, f.Major)
+		case p.field == fieldMinor:
+			fmt.Fprintf(b, "%0*d", f.MinorDigits, f.Minor)
+			for i, d := range f.Digits()[2:] {
+				fmt.Fprintf(b, "%s%0*d", s.separator, d, f.Sub[i])
+			}
+		case p.field == fieldDescriptor:
+			b.WriteString(f.Descriptor)
+		case p.field == fieldExtension:
+			b.WriteString(f.Extension)
+		default:
+			b.WriteString(p.literal)
+		}
+	}
+}
+
+func hasField(parts []templatePart, field string) bool {
+	for _, p := range parts {
+		if p.field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ref returns the schema to record in parsed values.  The default schema is recorded as nil so that values parsed
+// with it compare equal to literals which do not specify a schema.
+func (s *Schema) ref() *Schema {
+	if s == DefaultSchema {
+		return nil
+	}
+	return s
+}
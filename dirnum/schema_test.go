@@ -0,0 +1,136 @@
+package dirnum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaCustomSeparators(t *testing.T) {
+	s, err := NewSchema(SchemaConfig{
+		Template:   "IMG-{major}[_{minor}][ {descriptor}].{extension}",
+		Extensions: []string{"jpg"},
+	})
+	assert.NoError(t, err)
+
+	f, err := s.Parse("IMG-1_2 tag, other.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, f.Major)
+	assert.Equal(t, 2, f.Minor)
+	assert.Equal(t, []string{"tag", "other"}, f.Tags())
+	assert.Equal(t, "IMG-1_2 tag, other.jpg", f.String())
+
+	_, err = s.Parse("0001-02-tag.jpg")
+	assert.Error(t, err)
+
+	files := []string{"IMG-0.jpg", "IMG-2_0.jpg", "IMG-2_1 foo.jpg"}
+	errors, unused := s.ValidateFileNames(files, false, false)
+	assert.Len(t, errors, 1)
+	assert.Equal(t, CodeMajorGap, errors.All()[0].Code)
+	assert.ElementsMatch(t, []RenameEntry{
+		{OldName: "IMG-2_0.jpg", NewName: "IMG-1_0.jpg"},
+		{OldName: "IMG-2_1 foo.jpg", NewName: "IMG-1_1 foo.jpg"},
+	}, s.ComputeRenames(files, unused))
+}
+
+func TestSchemaPrefixAndDots(t *testing.T) {
+	s := MustSchema(SchemaConfig{Template: "p{major}[.{minor}].{extension}", Extensions: []string{"jpg", "png"}})
+
+	assert.ElementsMatch(t, []RenameEntry{
+		{OldName: "p1.jpg", NewName: "p1.0.jpg"},
+		{OldName: "p2.png", NewName: "p1.1.png"},
+	}, s.ComputeAppend([]string{"p0.jpg", "p1.jpg", "p2.png"}, 2, 1))
+
+	stats := s.ComputeStats([]string{"p1.jpg"})
+	assert.Empty(t, stats)
+}
+
+func TestSchemaCustomPattern(t *testing.T) {
+	s, err := NewSchema(SchemaConfig{
+		Template:   "{major}[-{minor}].{extension}",
+		Pattern:    `^(?i)scan(?P<major>[0-9]+)(?:-(?P<minor>[0-9]+))?\.(?P<extension>jpg)$`,
+		Extensions: []string{"jpg"},
+	})
+	assert.NoError(t, err)
+	f, err := s.Parse("SCAN12-3.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, "12-3.jpg", f.String())
+}
+
+func TestSchemaInvalid(t *testing.T) {
+	for _, template := range []string{
+		"{major}.jpg",                 // No extension
+		"{major}-{minor}.{extension}", // Minor not optional
+		"[{major}].{extension}",       // Major optional
+		"{major}[-{minor}-{descriptor}].{extension}",
+		"{major}[-{minor}.{extension}",
+		"{major}{size}.{extension}",
+	} {
+		_, err := NewSchema(SchemaConfig{Template: template, Extensions: []string{"jpg"}})
+		assert.Error(t, err, template)
+	}
+
+	_, err := NewSchema(SchemaConfig{Template: DefaultSchemaConfig.Template})
+	assert.Error(t, err)
+
+	_, err = NewSchema(SchemaConfig{Template: DefaultSchemaConfig.Template, Extensions: []string{"jpg"}, Pattern: `^([0-9]+)\.jpg$`})
+	assert.Error(t, err)
+}
+
+func TestSchemaReservedMajors(t *testing.T) {
+	c := DefaultSchemaConfig
+	c.Reserved = []int{1, 2}
+	s := MustSchema(c)
+
+	files := []string{"0.jpg", "3.jpg", "5-beach.jpg"}
+	errors, unused := s.ValidateFileNames(files, false, false)
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "5-beach.jpg", errors.All()[0].File)
+	assert.Equal(t, []int{4}, unused)
+	assert.Equal(t, []RenameEntry{{OldName: "5-beach.jpg", NewName: "4-beach.jpg"}}, s.ComputeRenames(files, unused))
+
+	// Reserved numbers are not filled even if the caller reports them unused
+	assert.Equal(t, []RenameEntry{{OldName: "5-beach.jpg", NewName: "4-beach.jpg"}}, s.ComputeRenames(files, []int{1, 2, 4}))
+
+	errors, unused = s.ValidateFileNames([]string{"0.jpg", "3.jpg"}, false, false)
+	assert.Empty(t, errors)
+	assert.Empty(t, unused)
+}
+
+func TestSchemaFrozenMajors(t *testing.T) {
+	c := DefaultSchemaConfig
+	c.Frozen = []int{3}
+	s := MustSchema(c)
+
+	// The frozen group keeps its number and its gap in the minor numbering; the groups above it fill the gaps below
+	files := []string{"0.jpg", "3-0.jpg", "3-2.jpg", "5.jpg", "6-0.jpg", "6-1.jpg"}
+	errors, unused := s.ValidateFileNames(files, true, true)
+	assert.Empty(t, errors)
+	assert.Equal(t, []int{1, 2, 4}, unused)
+	assert.ElementsMatch(t, []RenameEntry{
+		{OldName: "5.jpg", NewName: "1.jpg"},
+		{OldName: "6-0.jpg", NewName: "2-0.jpg"},
+		{OldName: "6-1.jpg", NewName: "2-1.jpg"},
+	}, s.ComputeRenames(files, unused))
+	assert.Empty(t, s.ComputeFixes(files))
+	// Nothing moves when every group is frozen
+	assert.Empty(t, s.ComputeRenames([]string{"3-0.jpg", "3-2.jpg"}, []int{0, 1, 2}))
+
+	_, err := s.ComputeAppendVersion(files, []int{3}, []int{0})
+	assert.ErrorContains(t, err, "major group 3 is frozen")
+	_, err = s.ComputeAppendVersion(files, []int{5}, []int{3})
+	assert.ErrorContains(t, err, "major group 3 is frozen")
+	_, err = s.ComputeSplit(files, []int{3, 2}, []int{7})
+	assert.ErrorContains(t, err, "major group 3 is frozen")
+	_, err = s.ComputeSplit(files, []int{6, 1}, []int{3, 7})
+	assert.ErrorContains(t, err, "major group 3 is frozen")
+	_, err = s.ComputeSplit(files, []int{6, 1}, []int{7})
+	assert.NoError(t, err)
+
+	c.Reserved = []int{3}
+	_, err = NewSchema(c)
+	assert.Error(t, err)
+	c.Reserved, c.Frozen = nil, []int{-1}
+	_, err = NewSchema(c)
+	assert.Error(t, err)
+}
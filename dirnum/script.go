@@ -0,0 +1,67 @@
+package dirnum
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ShellScript renders renames as a POSIX shell script of mv commands, for auditing or applying a plan without
+// dirnum.  The script works in the directory given as its argument, if any, or else in dir, or else in the
+// directory it is run from.  Like ApplyRenames, it moves files through temporary names when one file is renamed to
+// the old name of another, and creates the subdirectories renamed files move into.  It stops without overwriting
+// anything if a target already exists.  It does not update the journal or checksum manifest.
+func ShellScript(dir, description string, renames []RenameEntry) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	for _, line := range strings.Split(description, "\n") {
+		fmt.Fprintf(&b, "# %s\n", line)
+	}
+	b.WriteString("set -eu\n\n")
+	if dir != "" {
+		fmt.Fprintf(&b, "if [ $# -gt 0 ]; then cd -- \"$1\"; else cd -- %s; fi\n\n", ShellQuote(dir))
+	} else {
+		b.WriteString("if [ $# -gt 0 ]; then cd -- \"$1\"; fi\n\n")
+	}
+	b.WriteString(`rename() {
+	if [ -e "$2" ] || [ -L "$2" ]; then
+		echo "not renaming $1: $2 already exists" >&2
+		exit 1
+	fi
+	mv -- "$1" "$2"
+}
+
+`)
+
+	subs := make(map[string]bool)
+	for _, r := range renames {
+		if sub := filepath.Dir(r.NewName); sub != "." {
+			subs[filepath.ToSlash(sub)] = true
+		}
+	}
+	sorted := make([]string, 0, len(subs))
+	for sub := range subs {
+		sorted = append(sorted, sub)
+	}
+	sort.Strings(sorted)
+	for _, sub := range sorted {
+		fmt.Fprintf(&b, "mkdir -p -- %s\n", ShellQuote(sub))
+	}
+
+	for _, step := range renameSteps(renames) {
+		fmt.Fprintf(&b, "rename %s %s\n", ShellQuote(filepath.ToSlash(step.OldName)), ShellQuote(filepath.ToSlash(step.NewName)))
+	}
+	return b.String()
+}
+
+// UndoShellScript renders the script which reverses renames, as ShellScript does for the renames themselves
+func UndoShellScript(dir, description string, renames []RenameEntry) string {
+	return ShellScript(dir, description, Reverse(renames))
+}
+
+// ShellQuote quotes a string as a single word for a POSIX shell.  Within single quotes every character is literal
+// except the single quote itself, which is written as '\''.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
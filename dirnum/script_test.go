@@ -0,0 +1,45 @@
+package dirnum
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'0001-beach.jpg'`, ShellQuote("0001-beach.jpg"))
+	assert.Equal(t, `'0001-Bob'\''s house, $HOME.jpg'`, ShellQuote("0001-Bob's house, $HOME.jpg"))
+}
+
+func TestShellScript(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	dir := t.TempDir()
+	original := map[string]string{"0-it's.jpg": "a", "1-a b.jpg": "b", "-2.jpg": "c"}
+	writeFiles(t, dir, original)
+	// Swapping two files requires temporary names, and one file moves into a subdirectory
+	renames := []RenameEntry{
+		{OldName: "0-it's.jpg", NewName: "1-a b.jpg"},
+		{OldName: "1-a b.jpg", NewName: "0-it's.jpg"},
+		{OldName: "-2.jpg", NewName: "trash/-2.jpg"},
+	}
+
+	run := func(script string) {
+		cmd := exec.Command("sh", "-c", script, "script", dir)
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+	run(ShellScript("", "test", renames))
+	assert.Equal(t, map[string]string{"0-it's.jpg": "b", "1-a b.jpg": "a"}, readFiles(t, dir))
+	run(UndoShellScript("", "test undo", renames))
+	assert.Equal(t, original, readFiles(t, dir))
+
+	// Existing files are never overwritten
+	writeFiles(t, dir, map[string]string{"5.jpg": "d"})
+	out, err := exec.Command("sh", "-c", ShellScript("", "test", []RenameEntry{{OldName: "-2.jpg", NewName: "5.jpg"}}), "script", dir).CombinedOutput()
+	assert.Error(t, err)
+	assert.Contains(t, string(out), "already exists")
+	assert.Equal(t, "d", readFiles(t, dir)["5.jpg"])
+}
@@ -0,0 +1,73 @@
+package dirnum
+
+import (
+	"sort"
+)
+
+// MetadataStat counts the number of times a tag is seen
+type MetadataStat struct {
+	Tag   string
+	Files []string // The files carrying the tag
+
+	schema *Schema // The schema the files were parsed with; nil for DefaultSchema
+}
+
+// ComputeStats gathers tag statistics using DefaultSchema
+func ComputeStats(fileNames []string) []MetadataStat {
+	return DefaultSchema.ComputeStats(fileNames)
+}
+
+// ComputeStats looks through a list of filenames, gathers the list of tags, and counts how many times each is referenced.
+func (s *Schema) ComputeStats(fileNames []string) []MetadataStat {
+	tagMap := make(map[string][]string)
+	for _, f := range fileNames {
+		parsed, err := s.Parse(f)
+		if err != nil {
+			continue // Skip files that don't match the expected format
+		}
+
+		for _, t := range parsed.Tags() {
+			tagMap[t] = append(tagMap[t], f)
+		}
+	}
+
+	stats := make([]MetadataStat, 0, len(tagMap))
+	for tag, files := range tagMap {
+		stats = append(stats, MetadataStat{Tag: tag, Files: files, schema: s.ref()})
+	}
+	return stats
+}
+
+// Majors returns the distinct major versions of the files carrying the tag, in ascending order
+func (s MetadataStat) Majors() []int {
+	schema := s.schema
+	if schema == nil {
+		schema = DefaultSchema
+	}
+	var majors []int
+	majorSet := make(map[int]bool)
+	for _, f := range s.Files {
+		parsed, err := schema.Parse(f)
+		if err == nil && !majorSet[parsed.Major] {
+			majorSet[parsed.Major] = true
+			majors = append(majors, parsed.Major)
+		}
+	}
+	sort.Ints(majors)
+	return majors
+}
+
+func SortStatsAlphabetical(stats []MetadataStat) {
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Tag < stats[j].Tag
+	})
+}
+
+func SortStatsByFrequency(stats []MetadataStat) {
+	sort.Slice(stats, func(i, j int) bool {
+		if len(stats[i].Files) == len(stats[j].Files) {
+			return stats[i].Tag < stats[j].Tag
+		}
+		return len(stats[i].Files) > len(stats[j].Files) // descending frequency
+	})
+}
@@ -0,0 +1,410 @@
+package dirnum
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrorCode identifies the kind of problem found during validation.  Codes are stable across releases and are
+// safe to match on; the accompanying message text is not.
+type ErrorCode string
+
+const (
+	CodeBadFilename     ErrorCode = "bad-filename"     // The name does not match the naming schema
+	CodeOverriddenMajor ErrorCode = "overridden-major" // Two files share a major number and neither has a minor number
+	CodeDuplicateMinor  ErrorCode = "duplicate-minor"  // Two files share the same major and minor number
+	CodeMajorGap        ErrorCode = "major-gap"        // The major numbering skips one or more values
+	CodeMinorOnSingle   ErrorCode = "minor-on-single"  // A minor number is used on the only file in a major group
+	CodeMinorStart      ErrorCode = "minor-start"      // The minor numbering of a group does not start at 0
+	CodeMinorGap        ErrorCode = "minor-gap"        // The minor numbering skips one or more values
+	CodeStaleBaseline   ErrorCode = "stale-baseline"   // A baseline entry no longer matches any error
+)
+
+// Fixable reports whether problems with this code are resolved by the renames dirnum proposes
+func (c ErrorCode) Fixable() bool {
+	switch c {
+	case CodeOverriddenMajor, CodeDuplicateMinor, CodeMajorGap, CodeMinorOnSingle, CodeMinorStart, CodeMinorGap,
+		CodeExtensionMismatch, CodeChronology:
+		return true
+	}
+	return false
+}
+
+// Severity indicates how serious a validation error is
+type Severity string
+
+const (
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+var severityRank = map[Severity]int{SeverityWarning: 0, SeverityError: 1}
+
+// ParseSeverity converts a severity name into a Severity
+func ParseSeverity(s string) (Severity, error) {
+	if _, found := severityRank[Severity(s)]; !found {
+		return "", fmt.Errorf("unknown severity %q", s)
+	}
+	return Severity(s), nil
+}
+
+// AtLeast reports whether s is as severe as or more severe than other
+func (s Severity) AtLeast(other Severity) bool {
+	return severityRank[s] >= severityRank[other]
+}
+
+// ValidationError describes a single problem with a single file
+type ValidationError struct {
+	Code     ErrorCode
+	Severity Severity
+	File     string
+	Major    int      // NoVersion if the file name could not be parsed
+	Minor    int      // NoVersion if the file has no minor version or could not be parsed
+	Related  []string // Other files involved in the problem, such as the other half of a duplicate
+	Message  string   // Human-readable description, including the file name
+}
+
+func (e ValidationError) Error() string {
+	return e.Message
+}
+
+// MarshalJSON renders missing version numbers as null rather than as NoVersion
+func (e ValidationError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code     ErrorCode `json:"code"`
+		Severity Severity  `json:"severity"`
+		File     string    `json:"file"`
+		Major    *int      `json:"major"`
+		Minor    *int      `json:"minor"`
+		Related  []string  `json:"related,omitempty"`
+		Message  string    `json:"message"`
+	}{e.Code, e.Severity, e.File, jsonVersion(e.Major), jsonVersion(e.Minor), e.Related, e.Message})
+}
+
+// jsonVersion returns a version number to marshal, which is nil for NoVersion
+func jsonVersion(v int) *int {
+	if v == NoVersion {
+		return nil
+	}
+	return &v
+}
+
+// ValidationErrors maps from a file name to the problems found with that file
+type ValidationErrors map[string][]ValidationError
+
+// Merge adds all of the errors in other
+func (v ValidationErrors) Merge(other ValidationErrors) {
+	for _, e := range other.All() {
+		v.add(e)
+	}
+}
+
+func (v ValidationErrors) add(e ValidationError) {
+	v[e.File] = append(v[e.File], e)
+}
+
+// Involving returns the errors which concern any of the given files, either directly or as a related file
+func (errors ValidationErrors) Involving(files []string) ValidationErrors {
+	wanted := make(map[string]bool)
+	for _, f := range files {
+		wanted[f] = true
+	}
+	result := make(ValidationErrors)
+	for _, e := range errors.All() {
+		involved := wanted[e.File]
+		for _, r := range e.Related {
+			involved = involved || wanted[r]
+		}
+		if involved {
+			result.add(e)
+		}
+	}
+	return result
+}
+
+// All returns every validation error, ordered by file name
+func (errors ValidationErrors) All() []ValidationError {
+	filesWithErrors := []string{}
+	for f := range errors {
+		filesWithErrors = append(filesWithErrors, f)
+	}
+	sort.Strings(filesWithErrors)
+	all := []ValidationError{}
+	for _, f := range filesWithErrors {
+		all = append(all, errors[f]...)
+	}
+	return all
+}
+
+// String renders the errors as human-readable text, one error per line
+func (errors ValidationErrors) String() string {
+	if len(errors) == 0 {
+		return "No errors found"
+	}
+
+	var sb strings.Builder
+	for _, e := range errors.All() {
+		sb.WriteString(fmt.Sprintf("\"%s\": %s\n", e.File, e.Message))
+	}
+	return sb.String()
+}
+
+// JSON renders the errors as a JSON array ordered by file name
+func (errors ValidationErrors) JSON() ([]byte, error) {
+	return json.MarshalIndent(errors.All(), "", "  ")
+}
+
+// Lines renders the errors in a line-oriented format suitable for grep and awk: one error per line, with the
+// file, severity, code and message separated by tabs.
+func (errors ValidationErrors) Lines() string {
+	var sb strings.Builder
+	for _, e := range errors.All() {
+		sb.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\n", e.File, e.Severity, e.Code, e.Message))
+	}
+	return sb.String()
+}
+
+// Format renders the errors in the named output format: "text", "json" or "lines"
+func (errors ValidationErrors) Format(format string) (string, error) {
+	switch format {
+	case "text":
+		return errors.String(), nil
+	case "json":
+		b, err := errors.JSON()
+		return string(b), err
+	case "lines":
+		return errors.Lines(), nil
+	}
+	return "", fmt.Errorf("unknown output format %q", format)
+}
+
+// versionNode is a node of the version hierarchy: the root, a major group, a minor group within it and so on.  A
+// file is stored at the node for its full version, under the child NoVersion, so "12.jpg" is at 12/NoVersion and
+// "12-3.jpg" at 12/3/NoVersion.
+type versionNode struct {
+	file     string
+	children map[int]*versionNode
+}
+
+// add records a file under the given path of version components, returning the file already there, if any
+func (n *versionNode) add(path []int, file string) string {
+	for _, v := range path {
+		if n.children == nil {
+			n.children = make(map[int]*versionNode)
+		}
+		child, found := n.children[v]
+		if !found {
+			child = &versionNode{}
+			n.children[v] = child
+		}
+		n = child
+	}
+	if n.file != "" {
+		return n.file
+	}
+	n.file = file
+	return ""
+}
+
+// keys returns the components of the node's children in ascending order
+func (n *versionNode) keys() []int {
+	keys := make([]int, 0, len(n.children))
+	for k := range n.children {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// first returns the lowest-numbered file below the node
+func (n *versionNode) first() string {
+	if n.file != "" {
+		return n.file
+	}
+	for _, k := range n.keys() {
+		if f := n.children[k].first(); f != "" {
+			return f
+		}
+	}
+	return ""
+}
+
+// ValidateFileNames validates file names using DefaultSchema
+func ValidateFileNames(files []string, ignoreMajor, ignoreMinorZero bool) (ValidationErrors, []int) {
+	return DefaultSchema.ValidateFileNames(files, ignoreMajor, ignoreMinorZero)
+}
+
+// Returns any errors found and a list of any skipped major version numbers.  Files may be within group directories,
+// as listed by ReadGroupFiles, in which case they must belong to the directory's major group.  The numbering below
+// each major number is checked at every level of the version hierarchy, except within frozen groups.  Reserved
+// major numbers are neither reported as gaps nor returned as skipped.
+func (s *Schema) ValidateFileNames(files []string, ignoreMajor, ignoreMinorZero bool) (ValidationErrors, []int) {
+	errors := make(ValidationErrors)
+	root := &versionNode{}
+	parsed := make(map[string]*FileNamePieces)
+	for _, f := range files {
+		if err := CheckPortableName(filepath.Base(f)); err != nil {
+			errors.add(ValidationError{
+				Code:     CodeUnsafeName,
+				Severity: SeverityError,
+				File:     f,
+				Major:    NoVersion,
+				Minor:    NoVersion,
+				Message:  fmt.Sprintf("Name %v: %s", err, f),
+			})
+			continue
+		}
+		name, err := s.Parse(filepath.Base(f))
+		if err != nil {
+			errors.add(ValidationError{
+				Code:     CodeBadFilename,
+				Severity: SeverityError,
+				File:     f,
+				Major:    NoVersion,
+				Minor:    NoVersion,
+				Message:  strings.TrimSuffix(err.Error(), filepath.Base(f)) + f,
+			})
+			continue
+		}
+		if group, isGroup := GroupDirectoryMajor(filepath.Dir(f)); isGroup && group != name.Major {
+			errors.add(ValidationError{
+				Code:     CodeGroupMismatch,
+				Severity: SeverityError,
+				File:     f,
+				Major:    name.Major,
+				Minor:    name.Minor,
+				Message:  fmt.Sprintf("File in group directory %s has major number %d: %s", filepath.Dir(f), name.Major, f),
+			})
+			continue
+		}
+		parsed[f] = name
+		versions := name.Versions()
+		oldFile := root.add(append(versions, NoVersion), f)
+		if oldFile == "" {
+			continue
+		}
+		e := ValidationError{Severity: SeverityError, Major: name.Major, Minor: name.Minor}
+		if name.Minor == NoVersion {
+			e.Code = CodeOverriddenMajor
+			e.Message = fmt.Sprintf("Overridden Major Number %d for files: \"%s\", \"%s\"", name.Major, oldFile, f)
+		} else {
+			e.Code = CodeDuplicateMinor
+			e.Message = fmt.Sprintf("Duplicate Major/Minor %s for files: \"%s\", \"%s\"", FormatVersion(versions), oldFile, f)
+		}
+		e.File, e.Related = f, []string{oldFile}
+		errors.add(e)
+		e.File, e.Related = oldFile, []string{f}
+		errors.add(e)
+	}
+
+	// Each partially filled error describes the lowest-numbered file of the group it is reported for
+	report := func(e ValidationError, n *versionNode) {
+		e.File = n.first()
+		e.Major, e.Minor = parsed[e.File].Major, parsed[e.File].Minor
+		e.Message = fmt.Sprintf(e.Message, e.File)
+		errors.add(e)
+	}
+
+	majErrors, unused := validateMajor(root.keys(), ignoreMajor, s.IsReserved)
+	for n, e := range majErrors {
+		report(e, root.children[n])
+	}
+
+	var validateLevels func(n *versionNode)
+	validateLevels = func(n *versionNode) {
+		for v, e := range validateMinor(n.keys(), ignoreMinorZero) {
+			report(e, n.children[v])
+		}
+		for _, child := range n.children {
+			if child.children != nil {
+				validateLevels(child)
+			}
+		}
+	}
+	for n, major := range root.children {
+		// Frozen groups cannot be renumbered, so gaps within them are not worth reporting
+		if !s.IsFrozen(n) {
+			validateLevels(major)
+		}
+	}
+
+	sort.Ints(unused)
+	return errors, unused
+}
+
+// Returns an map from major version number to a partially filled error whose message is a format string which
+// accepts the file name.  Reserved numbers are neither gaps nor unused.
+func validateMajor(nums []int, ignoreMajor bool, reserved func(int) bool) (map[int]ValidationError, []int) {
+	unused := []int{}
+	errors := make(map[int]ValidationError)
+	prev := -1
+	for _, n := range nums {
+		start := prev + 1
+		if start < 0 {
+			start = 0
+		}
+		gap := false
+		for i := start; i < n; i++ {
+			if !reserved(i) {
+				unused = append(unused, i)
+				gap = true
+			}
+		}
+		if gap && !ignoreMajor {
+			errors[n] = ValidationError{
+				Code:     CodeMajorGap,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("Numbering jumped from %d to %d: %%s", prev, n),
+			}
+		}
+		prev = n
+	}
+
+	return errors, unused
+}
+
+// Returns an map from minor version number to a partially filled error whose message is a format string which
+// accepts the file name
+func validateMinor(nums []int, ignoreMinorZero bool) map[int]ValidationError {
+	errors := make(map[int]ValidationError)
+	if len(nums) == 1 {
+		if nums[0] != NoVersion {
+			errors[nums[0]] = ValidationError{
+				Code:     CodeMinorOnSingle,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("Minor version %d on single file: %%s", nums[0]),
+			}
+		}
+	} else if len(nums) > 1 {
+		prev := -1
+		for _, n := range nums {
+			if n != (prev + 1) {
+				if prev == -1 || prev == NoVersion {
+					if !ignoreMinorZero {
+						errors[n] = ValidationError{
+							Code:     CodeMinorStart,
+							Severity: SeverityWarning,
+							Message:  "Minor version numbering must start with 0: %s",
+						}
+					}
+				} else {
+					errors[n] = ValidationError{
+						Code:     CodeMinorGap,
+						Severity: SeverityWarning,
+						Message:  fmt.Sprintf("Minor numbering jumped from %d to %d: %%s", prev, n),
+					}
+				}
+			}
+			prev = n
+			// In the case of "0-Foo, 0-1" we do not want a duplicate error on both 1 (which didn't start at 0) and 0 (which comes alphabetically after)
+			if n == NoVersion {
+				prev = 0
+			}
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,111 @@
+package dirnum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDeepVersions(t *testing.T) {
+	f, err := ParseFileName("0012-03-01-Foo.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{12, 3, 1}, f.Versions())
+	assert.Equal(t, "Foo", f.Descriptor)
+	f.SetVersions([]int{12, 3, 1, 0}, []int{4, 2, 2, 1})
+	assert.Equal(t, "0012-03-01-0-Foo.jpg", f.String())
+
+	s := MustSchema(SchemaConfig{Template: "p{major}[.{minor}].{extension}", Extensions: []string{"jpg"}})
+	f, err = s.Parse("p1.3.1.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 3, 1}, f.Versions())
+	assert.Equal(t, "p1.3.1.jpg", f.String())
+
+	_, err = ParseFileName("12-3-.jpg")
+	assert.Error(t, err)
+}
+
+func TestParseVersion(t *testing.T) {
+	v, err := ParseVersion("12-3.1")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{12, 3, 1}, v)
+	for _, bad := range []string{"", "12-", "-1", "a"} {
+		_, err := ParseVersion(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestValidateDeepVersions(t *testing.T) {
+	files := []string{"0.jpg", "1-0-0.jpg", "1-0-2.jpg", "1-1.jpg", "1-1-0.jpg", "1-1-1.jpg", "2-0-0-0.jpg", "2-1.jpg", "2-1-0-tag.jpg"}
+	errors, _ := ValidateFileNames(files, true, false)
+
+	codes := make(map[string][]ErrorCode)
+	for _, e := range errors.All() {
+		codes[e.File] = append(codes[e.File], e.Code)
+	}
+	// As with minor versions, a file without a component counts as 0 within its group
+	assert.Equal(t, map[string][]ErrorCode{
+		"1-0-2.jpg":     {CodeMinorGap},
+		"1-1.jpg":       {CodeMinorStart},
+		"1-1-0.jpg":     {CodeMinorGap},
+		"2-0-0-0.jpg":   {CodeMinorOnSingle, CodeMinorOnSingle},
+		"2-1.jpg":       {CodeMinorStart},
+		"2-1-0-tag.jpg": {CodeMinorGap},
+	}, codes)
+
+	errors, _ = ValidateFileNames([]string{"3-1-2.jpg", "3-1-2-tag.jpg"}, true, true)
+	assert.Equal(t, CodeDuplicateMinor, errors["3-1-2.jpg"][0].Code)
+	assert.Contains(t, errors["3-1-2.jpg"][0].Message, "3-1-2")
+}
+
+func TestRenumberDeepVersions(t *testing.T) {
+	files := []string{"0-0-0.jpg", "0-0-2.jpg", "0-3.jpg", "1-2-0.jpg", "1-2-1-tag.jpg", "2-1.jpg", "2-1-1.jpg", "2-4-5.jpg"}
+	assert.ElementsMatch(t, []RenameEntry{
+		{OldName: "0-0-2.jpg", NewName: "0-0-1.jpg"},
+		{OldName: "0-3.jpg", NewName: "0-1.jpg"},
+		// A single sub-group is merged into its parent
+		{OldName: "1-2-0.jpg", NewName: "1-0.jpg"},
+		{OldName: "1-2-1-tag.jpg", NewName: "1-1-tag.jpg"},
+		// A file without a component is numbered first, and a lone file loses the component
+		{OldName: "2-1.jpg", NewName: "2-0-0.jpg"},
+		{OldName: "2-1-1.jpg", NewName: "2-0-1.jpg"},
+		{OldName: "2-4-5.jpg", NewName: "2-1.jpg"},
+	}, ComputeRenames(files, nil))
+
+	errors, _ := ValidateFileNames(RenamedNames(files, ComputeRenames(files, nil)), false, false)
+	assert.Empty(t, errors)
+}
+
+func TestAppendAndSplitVersions(t *testing.T) {
+	files := []string{"1-0.jpg", "1-1-0.jpg", "1-1-1.jpg", "2-0.jpg", "2-1-0.jpg", "2-1-1-tag.jpg"}
+
+	renames, err := ComputeAppendVersion(files, []int{2}, []int{1})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []RenameEntry{
+		{OldName: "2-0.jpg", NewName: "1-2.jpg"},
+		{OldName: "2-1-0.jpg", NewName: "1-3-0.jpg"},
+		{OldName: "2-1-1-tag.jpg", NewName: "1-3-1-tag.jpg"},
+	}, renames)
+
+	renames, err = ComputeAppendVersion(files, []int{2, 1}, []int{1, 0})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []RenameEntry{
+		{OldName: "1-0.jpg", NewName: "1-0-0.jpg"},
+		{OldName: "2-1-0.jpg", NewName: "1-0-1.jpg"},
+		{OldName: "2-1-1-tag.jpg", NewName: "1-0-2-tag.jpg"},
+	}, renames)
+
+	_, err = ComputeAppendVersion(files, []int{1, 1}, []int{1})
+	assert.Error(t, err)
+
+	renames, err = ComputeSplit(files, []int{1, 1}, []int{3})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []RenameEntry{
+		{OldName: "1-1-0.jpg", NewName: "3-0.jpg"},
+		{OldName: "1-1-1.jpg", NewName: "3-1.jpg"},
+	}, renames)
+
+	_, err = ComputeSplit(files, []int{1, 1}, []int{2})
+	assert.Error(t, err)
+	_, err = ComputeSplit(files, []int{4}, []int{5})
+	assert.Error(t, err)
+}
@@ -0,0 +1,26 @@
+package dirnum
+
+import "sync"
+
+// forEach calls fn for every item, using a pool of workers goroutines.  fn must be safe to call concurrently.
+func forEach[T any](items []T, workers int, fn func(T)) {
+	if workers < 1 {
+		workers = 1
+	}
+	work := make(chan T)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range work {
+				fn(item)
+			}
+		}()
+	}
+	for _, item := range items {
+		work <- item
+	}
+	close(work)
+	wg.Wait()
+}
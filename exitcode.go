@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/beckbria/dirnum/dirnum"
+)
+
+// Exit codes returned by dirnum.  In check mode the exit code reflects the most serious class of validation error
+// found; otherwise dirnum exits with ExitOK unless it was used incorrectly or encountered an error.
+const (
+	ExitOK            = 0
+	ExitInvalid       = 1 // Validation found problems that renumbering cannot fix, such as bad file names
+	ExitUsage         = 2 // The command line was invalid (matches the exit code used by the flag package)
+	ExitFixable       = 3 // Validation found only problems that renumbering would fix
+	ExitInternalError = 4 // dirnum could not complete, e.g. because the directory could not be read
+)
+
+// Classes of validation error which may be selected with -fail-on
+const (
+	classInvalid = "invalid"
+	classFixable = "fixable"
+)
+
+// failOnClasses parses the classes of error which fail a check
+func failOnClasses(list []string) (map[string]bool, error) {
+	classes := make(map[string]bool)
+	for _, c := range list {
+		switch c {
+		case classInvalid, classFixable:
+			classes[c] = true
+		default:
+			return nil, fmt.Errorf("unknown error class %q: expected %q or %q", c, classInvalid, classFixable)
+		}
+	}
+	return classes, nil
+}
+
+// checkExitCode determines the exit code for check mode.  Errors less severe than minSeverity, and errors whose
+// class is not in failOn, do not affect the result.
+func checkExitCode(errors dirnum.ValidationErrors, failOn map[string]bool, minSeverity dirnum.Severity) int {
+	code := ExitOK
+	for _, e := range errors.All() {
+		if !e.Severity.AtLeast(minSeverity) {
+			continue
+		}
+		if e.Code.Fixable() {
+			if failOn[classFixable] && code == ExitOK {
+				code = ExitFixable
+			}
+		} else if failOn[classInvalid] {
+			return ExitInvalid
+		}
+	}
+	return code
+}
+
+// fatal reports an error which prevented dirnum from completing and exits
+func fatal(err error) {
+	releaseLock()
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(ExitInternalError)
+}
+
+// usageError reports an invalid command line and exits
+func usageError(err error) {
+	releaseLock()
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(ExitUsage)
+}
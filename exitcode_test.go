@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/beckbria/dirnum/dirnum"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckExitCode(t *testing.T) {
+	all := map[string]bool{classInvalid: true, classFixable: true}
+	invalidOnly := map[string]bool{classInvalid: true}
+
+	clean, _ := dirnum.ValidateFileNames([]string{"0.jpg", "1.jpg"}, false, false)
+	assert.Equal(t, ExitOK, checkExitCode(clean, all, dirnum.SeverityWarning))
+
+	gap, _ := dirnum.ValidateFileNames([]string{"0.jpg", "2.jpg"}, false, false)
+	assert.Equal(t, ExitFixable, checkExitCode(gap, all, dirnum.SeverityWarning))
+	assert.Equal(t, ExitOK, checkExitCode(gap, invalidOnly, dirnum.SeverityWarning))
+	assert.Equal(t, ExitOK, checkExitCode(gap, all, dirnum.SeverityError))
+
+	bad, _ := dirnum.ValidateFileNames([]string{"0.jpg", "2.jpg", "foo.jpg"}, false, false)
+	assert.Equal(t, ExitInvalid, checkExitCode(bad, all, dirnum.SeverityWarning))
+	assert.Equal(t, ExitFixable, checkExitCode(bad, map[string]bool{classFixable: true}, dirnum.SeverityWarning))
+}
+
+func TestFailOnClasses(t *testing.T) {
+	classes, err := failOnClasses(splitList("invalid, fixable"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{classInvalid: true, classFixable: true}, classes)
+
+	classes, err = failOnClasses(splitList(""))
+	assert.NoError(t, err)
+	assert.Empty(t, classes)
+
+	_, err = failOnClasses([]string{"warning"})
+	assert.Error(t, err)
+}
@@ -1,109 +0,0 @@
-package main
-
-import (
-	"fmt"
-	"sort"
-	"strconv"
-	"strings"
-)
-
-const NoVersion = -99 // Indicates a file with no minor version
-
-type FileNamePieces struct {
-	major, minor, majorDigits, minorDigits int
-	originalName, descriptor, extension    string
-}
-
-func (f *FileNamePieces) String() string {
-	var b strings.Builder
-	b.Grow(len(f.originalName))
-	fmt.Fprintf(&b, "%0*d", f.majorDigits, f.major)
-	if f.minor != NoVersion {
-		b.WriteRune('-')
-		fmt.Fprintf(&b, "%0*d", f.minorDigits, f.minor)
-	}
-	if len(f.descriptor) > 0 {
-		// The descriptor includes the leading dash
-		b.WriteString(f.descriptor)
-	}
-	b.WriteRune('.')
-	b.WriteString(f.extension)
-	return b.String()
-}
-
-func ParseFileName(f string) (*FileNamePieces, error) {
-	tokens := fileRegEx.FindStringSubmatch(f)
-	if tokens == nil {
-		return nil, fmt.Errorf("bad filename: %s", f)
-	}
-	major, err := strconv.Atoi(tokens[1])
-	if err != nil {
-		return nil, fmt.Errorf("invalid major version \"%s\": %s", tokens[1], f)
-	}
-	minor := NoVersion
-	if len(tokens[2]) > 0 {
-		minorStr := tokens[2][1:]
-		m, err := strconv.Atoi(minorStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid minor version \"%s\": %s", minorStr, f)
-		}
-		minor = m
-	}
-	minorDigits := 0
-	if minor != NoVersion {
-		minorDigits = len(strconv.Itoa(minor))
-	}
-	name := FileNamePieces{
-		major:        major,
-		minor:        minor,
-		majorDigits:  len(strconv.Itoa(major)),
-		minorDigits:  minorDigits,
-		descriptor:   tokens[3],
-		extension:    tokens[4],
-		originalName: f,
-	}
-	if name.extension == "jpeg" {
-		name.extension = "jpg"
-	}
-	return &name, nil
-}
-
-func ParseFileNames(fileNames []string) PFnpSlice {
-	files := make(PFnpSlice, 0)
-	for _, f := range fileNames {
-		n, err := ParseFileName(f)
-		if err == nil {
-			// Don't try to rename files which aren't named correctly.  Errors are displayed
-			// before this function and controlled by the quiet flag.
-			files = append(files, n)
-		}
-	}
-
-	// Sort the list by major/minor version
-	sort.Sort(files)
-	return files
-}
-
-// PFnpSlice represents a set of file names that can be sorted by major+minor version
-type PFnpSlice []*FileNamePieces
-
-func (s PFnpSlice) Len() int {
-	return len(s)
-}
-
-func (s PFnpSlice) Swap(i, j int) {
-	s[i], s[j] = s[j], s[i]
-}
-
-func (s PFnpSlice) Less(i, j int) bool {
-	first := s[i]
-	second := s[j]
-
-	if first.major < second.major {
-		return true
-	} else if first.major > second.major {
-		return false
-	}
-	// Major version is the same, compare minor version
-	return first.minor < second.minor
-}
@@ -1,151 +1,231 @@
 // dirnum reads the list of files in a directory and asserts that they are numbered in ascending order.
-// If they are not, it lists the out-of-order file names.
+// If they are not, it lists the out-of-order file names and can renumber them.
 // Supported groupings:
 // 0000.jpg, 0001.jpg, etc.
 // 0000-0.jpg, 0000-1.jpg, etc. - Minor versions for grouped files
+// 0000-0-0.jpg, 0000-0-1.jpg, etc. - Sub-series within a minor version, to any depth
 // 0000-note.jpg, 0000-0-note.jpg, etc. - Text annotations on file names
+//
+// Usage:
+//
+//	dirnum <command> [flags] <dir>
+//
+// Run "dirnum help" for the list of commands.  The flags of earlier versions ("dirnum -dir <dir> -renumber ...")
+// are still accepted when no command is given.
 package main
 
 import (
 	"bufio"
 	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/beckbria/dirnum/dirnum"
 )
 
 func main() {
-	dir := flag.String("dir", "", "The directory to analyze (mandatory)")
-	quiet := flag.Bool("quiet", false, "Do not print validation errors encountered")
-	ignoreMajor := flag.Bool("ignoremajor", true, "Do not print warnings for skips in the major version numbering")
-	ignoreMinorZero := flag.Bool("ignoreminorzero", true, "Do not print warnings for minor numbering skipping zero")
-	renumber := flag.Bool("renumber", true, "Renumber files to fill in gaps in major numbers")
-	stats := flag.Bool("stats", false, "Generate statistics on file naming")
-	statsSort := flag.String("stats-sort", "alpha", "Sort order for stats: 'alpha' (alphabetical) or 'freq' (frequency)")
-	statsNames := flag.Bool("stats-names", false, "Print tags and the major versions where they appear instead of counts")
-	exportTags := flag.Bool("export-tags", false, "Export files into subdirectories based on their tags")
-	exportPrefix := flag.String("export-prefix", "", "Optional prefix to filter tags for export")
-	exportMinCount := flag.Int("export-min-count", 0, "Only export tags that appear at least this many times")
-	appendFrom := flag.Int("append-from", -1, "The major version number to move files from")
-	appendOnto := flag.Int("append-onto", -1, "The major version number to append files onto")
-	flag.Parse()
-
-	if *exportTags {
-		isRenumberSet := false
-		flag.Visit(func(f *flag.Flag) {
-			if f.Name == "renumber" {
-				isRenumberSet = true
+	if len(os.Args) > 1 {
+		if c := findCommand(os.Args[1]); c != nil {
+			os.Exit(c.run(os.Args[2:]))
+		}
+		if arg := os.Args[1]; !strings.HasPrefix(arg, "-") {
+			if info, err := os.Stat(arg); err != nil || !info.IsDir() {
+				usageError(fmt.Errorf("unknown command %q: expected one of %s", arg, commandNames()))
 			}
-		})
-		if !isRenumberSet {
-			*renumber = false
 		}
 	}
+	os.Exit(runLegacy(os.Args[1:]))
+}
 
-	performAppend := *appendFrom >= 0 && *appendOnto >= 0
+// runLegacy implements the original single-command interface, in which flags select the operations to perform.
+// The operations run in a fixed order: validation, renumbering or appending, exporting and finally statistics.
+func runLegacy(args []string) int {
+	fs := flag.CommandLine
+	fs.Usage = func() {
+		printCommands()
+		fmt.Fprintf(os.Stderr, "\nWhen no command is given the following flags select the operations to perform:\n")
+		fs.PrintDefaults()
+	}
+	s := newSettings(fs)
+	s.checkFlags()
+	s.exportFlags("export-")
+	s.statsFlags("stats-")
+	quiet := fs.Bool("quiet", false, "Do not print validation errors encountered")
+	format := fs.String("format", "text", "Output format for validation errors: 'text', 'json' or 'lines'")
+	renumber := fs.Bool("renumber", true, "Renumber files to fill in gaps in major numbers")
+	stats := fs.Bool("stats", false, "Generate statistics on file naming")
+	exportTags := fs.Bool("export-tags", false, "Export files into subdirectories based on their tags")
+	appendFrom := fs.Int("append-from", -1, "The major version number to move files from")
+	appendOnto := fs.Int("append-onto", -1, "The major version number to append files onto")
+	check := fs.Bool("check", false, "Only validate, without prompting, and exit non-zero if errors are found")
+	writeBaselineFlag := fs.Bool("write-baseline", false, "Record the current validation errors as the baseline and exit")
+	printConfigFlag := fs.Bool("print-config", false, "Print the effective configuration, after applying "+dirnum.ConfigFileName+" files and flags, and exit")
+	s.parse(args)
 
-	if performAppend {
-		// If append is executing, disable standard renumbering unless explicitly asked?
-		// Usually we skip standard renumbering to avoid conflating the operations
-		*renumber = false
+	ws := s.resolve()
+	if *printConfigFlag {
+		printConfig(ws)
+		return ExitOK
+	}
+	if *writeBaselineFlag {
+		writeBaseline(ws)
+		return ExitOK
 	}
 
-	if *dir == "" {
-		fmt.Fprintf(os.Stderr, "Usage: %s\n", os.Args[0])
-		flag.PrintDefaults()
-		os.Exit(1)
+	// Exporting and appending each replace renumbering unless it is explicitly requested
+	if *exportTags && !s.isSet("renumber") {
+		*renumber = false
+	}
+	performAppend := *appendFrom >= 0 && *appendOnto >= 0
+	if performAppend {
+		*renumber = false
 	}
 
-	fileNames, err := ReadFileNames(*dir)
+	failClasses, err := failOnClasses(ws.cfg.Check.FailOn)
 	if err != nil {
-		log.Fatal(err)
+		usageError(err)
 	}
 
-	errors, unused := ValidateFileNames(fileNames, *ignoreMajor, *ignoreMinorZero)
+	errors, _ := ws.validate()
 	// Display errors for any malformed filenames
 	if !*quiet {
-		fmt.Println(errors)
+		printErrors(errors, *format)
+		printSubdirectories(ws, *format)
+	}
+
+	if *check {
+		return checkExitCode(errors, failClasses, ws.cfg.Check.FailSeverity)
 	}
 
-	// Determine file name changes
+	prompted := renameFlags{yes: new(bool), dryRun: new(bool), review: new(bool), savePlan: new(string), script: new(string), planHash: new(bool)}
 	if *renumber {
-		ren := ComputeRenames(fileNames, unused)
-		if len(ren) > 0 {
-			fmt.Println("\nProposed renames: ")
-			for _, r := range ren {
-				fmt.Printf("%s => %s\n", r.oldName, r.newName)
-			}
-			if prompt("Rename files?") {
-				for _, r := range ren {
-					RenameFile(r.oldName, r.newName, *dir)
-				}
-			}
-		} else {
-			fmt.Println("\nNo proposed renames.")
-		}
+		_, unused := ws.validateNames(ws.files)
+		ren := ws.schema.ComputeRenames(ws.renumberable(ws.files), unused)
+		proposeRenames(ws, "renumber", ren, prompted, "\nProposed renames: ", "\nNo proposed renames.")
 	}
 
 	if performAppend {
-		ren := ComputeAppend(fileNames, *appendFrom, *appendOnto)
-		if len(ren) > 0 {
-			fmt.Printf("\nProposed append from %d onto %d:\n", *appendFrom, *appendOnto)
-			for _, r := range ren {
-				fmt.Printf("%s => %s\n", r.oldName, r.newName)
-			}
-			if prompt("Rename files?") {
-				for _, r := range ren {
-					RenameFile(r.oldName, r.newName, *dir)
-				}
-			}
-		} else {
-			fmt.Println("\nNo proposed renames for append.")
+		ren, err := ws.schema.ComputeAppendVersion(ws.files, []int{*appendFrom}, []int{*appendOnto})
+		if err != nil {
+			usageError(err)
 		}
+		proposeRenames(ws, fmt.Sprintf("append %d onto %d", *appendFrom, *appendOnto), ren, prompted,
+			fmt.Sprintf("\nProposed append from %d onto %d:", *appendFrom, *appendOnto), "\nNo proposed renames for append.")
 	}
 
 	if *exportTags {
 		fmt.Println("")
-		if err := ExportTags(*dir, fileNames, *exportPrefix, *exportMinCount); err != nil {
-			log.Fatal(err)
+		if err := runExport(ws, false); err != nil {
+			fatal(err)
 		}
 	}
 
 	if *stats {
 		fmt.Println("")
-		computedStats := ComputeStats(fileNames)
-		if *statsSort == "freq" {
-			SortStatsByFrequency(computedStats)
-		} else {
-			SortStatsAlphabetical(computedStats)
-		}
+		printStats(ws)
+	}
+	return ExitOK
+}
+
+// Renames files, recording the operation in the directory's journal so that it can be undone
+func renameFiles(ws *workspace, operation string, ren []dirnum.RenameEntry) {
+	ws.lock(operation)
+	defer releaseLock()
+	// Another run may have renamed files after the plan was made but before the lock was acquired
+	files, _, err := dirnum.ReadEntries(ws.dir, ws.cfg.Ignore...)
+	if err != nil {
+		fatal(err)
+	}
+	if !slices.Equal(files, ws.files) {
+		fatal(fmt.Errorf("%s changed while the plan was being made; run dirnum again", ws.dir))
+	}
+	journal := ws.journal()
+	for _, r := range ren {
+		fmt.Printf("Renaming %s to %s\n", filepath.Join(ws.dir, r.OldName), filepath.Join(ws.dir, r.NewName))
+	}
+	if err := journal.Apply(operation, ren); err != nil {
+		fatal(err)
+	}
+}
+
+// Copies files into subdirectories based on their tags, after confirming with the user unless yes is set
+func runExport(ws *workspace, yes bool) error {
+	dir := ws.dir
+	exportPlan := ws.schema.PlanExport(ws.files, ws.cfg.Export.Prefix, ws.cfg.Export.MinCount)
+
+	if len(exportPlan) == 0 {
+		fmt.Println("No tags matching the given prefix were found.")
+		return nil
+	}
+
+	if conflictingDirs := dirnum.ExportConflicts(dir, exportPlan); len(conflictingDirs) > 0 {
+		return fmt.Errorf("cannot proceed, the following matching subdirectories already exist: %s", strings.Join(conflictingDirs, ", "))
+	}
+
+	// Count totals for prompt
+	numFiles := 0
+	for _, filesToExport := range exportPlan {
+		numFiles += len(filesToExport)
+	}
+
+	q := fmt.Sprintf("This will create %d subdirectories containing a total of %d files.  Continue?", len(exportPlan), numFiles)
+	if !yes && !prompt(q) {
+		return nil
+	}
 
-		if *statsNames {
-			PrintTagMajorVersions(computedStats)
-		} else {
-			PrintTagCounts(computedStats)
+	ws.lock("export")
+	defer releaseLock()
+	return dirnum.ExportTags(dir, exportPlan, func(tag, f string) {
+		fmt.Printf("Copying %s to %s\n", f, filepath.Join(tag, f))
+	})
+}
+
+func printTagCounts(stats []dirnum.MetadataStat) {
+	for _, s := range stats {
+		fmt.Printf("%d\t%s\n", len(s.Files), s.Tag)
+	}
+}
+
+func printTagMajorVersions(stats []dirnum.MetadataStat) {
+	for _, s := range stats {
+		majors := s.Majors()
+		majorStrs := make([]string, len(majors))
+		for i, m := range majors {
+			majorStrs[i] = strconv.Itoa(m)
 		}
+		fmt.Printf("%s\t%s\n", s.Tag, strings.Join(majorStrs, ", "))
 	}
 }
 
+// stdin is shared by every prompt, so that answers piped to dirnum are not lost in the buffer of an earlier prompt
+var stdin = bufio.NewReader(os.Stdin)
+
 // Prompts the user for a yes or no answer
 func prompt(q string) bool {
-	in := bufio.NewReader(os.Stdin)
+	return ask(stdin, q, "yn") == 'y'
+}
+
+// ask prompts until the user answers with one of the choices, which are single letters, and returns the answer
+func ask(in *bufio.Reader, q, choices string) byte {
 	for {
-		fmt.Printf("%s (y/n): ", q)
-		a, err := in.ReadString('\n')
-		if err != nil {
-			log.Fatal(err)
-		}
-		// Replace line endings
-		a = strings.TrimSpace(a)
-		if len(a) != 1 {
-			continue
-		}
-		switch strings.ToLower(a)[0] {
-		case 'y':
-			return true
-		case 'n':
-			return false
+		fmt.Printf("%s (%s): ", q, strings.Join(strings.Split(choices, ""), "/"))
+		a := readLine(in)
+		if len(a) == 1 && strings.Contains(choices, strings.ToLower(a)) {
+			return strings.ToLower(a)[0]
 		}
 	}
 }
+
+// readLine reads a line of input without its line ending
+func readLine(in *bufio.Reader) string {
+	a, err := in.ReadString('\n')
+	if err != nil && (err != io.EOF || a == "") {
+		fatal(err)
+	}
+	return strings.TrimSpace(a)
+}
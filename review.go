@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/beckbria/dirnum/dirnum"
+)
+
+// reviewHelp describes the answers accepted for each rename during review
+const reviewHelp = `y - accept this rename
+n - skip this rename
+e - edit the new name, then accept it
+g - accept this and the remaining renames of this major group
+s - skip this and the remaining renames of this major group
+a - accept this and all remaining renames
+q - skip this and all remaining renames`
+
+// reviewRenames asks the user to accept, skip or edit each rename in turn.  The reviewed plan is then checked: it
+// must still be possible to apply it without losing files, and the validation errors the result would have are
+// shown.  The user may apply the reviewed plan, review the proposed renames again or cancel.  It returns the plan to
+// apply, or nil if the user cancels.
+func reviewRenames(ws *workspace, ren []dirnum.RenameEntry, in *bufio.Reader) []dirnum.RenameEntry {
+	existing := slices.Concat(ws.files, ws.groupFiles, ws.dirs)
+	for {
+		reviewed := reviewEntries(ws.schema, ren, in)
+		if len(reviewed) == 0 {
+			fmt.Println("No renames accepted.")
+			return nil
+		}
+
+		fmt.Println("\nReviewed plan:")
+		for _, r := range reviewed {
+			fmt.Printf("%s => %s\n", r.OldName, r.NewName)
+		}
+		choices := "arc"
+		if err := dirnum.CheckRenames(existing, reviewed); err != nil {
+			fmt.Printf("The reviewed plan cannot be applied: %v\n", err)
+			choices = "rc"
+		} else if errors, _ := ws.validateNames(dirnum.RenamedNames(ws.files, reviewed)); len(errors) > 0 {
+			fmt.Println("After these renames the directory would have these errors:")
+			fmt.Print(errors.String())
+		} else {
+			fmt.Println("After these renames the directory would have no errors.")
+		}
+
+		switch ask(in, "Apply, review again or cancel?", choices) {
+		case 'a':
+			return reviewed
+		case 'c':
+			return nil
+		}
+	}
+}
+
+// reviewEntries asks about each rename in turn and returns those accepted, with any edits
+func reviewEntries(schema *dirnum.Schema, ren []dirnum.RenameEntry, in *bufio.Reader) []dirnum.RenameEntry {
+	// The major group of each rename, or -1 for files which do not match the schema
+	group := func(r dirnum.RenameEntry) int {
+		if f, err := schema.Parse(r.OldName); err == nil {
+			return f.Major
+		}
+		return -1
+	}
+	groupDecisions := make(map[int]byte) // 'y' or 'n' for groups accepted or skipped as a whole
+	var remaining byte                   // 'y' or 'n' once all remaining renames are accepted or skipped
+
+	var reviewed []dirnum.RenameEntry
+	lastGroup := -2
+	for _, r := range ren {
+		g := group(r)
+		decision := remaining
+		if decision == 0 {
+			decision = groupDecisions[g]
+		}
+		if decision == 0 && g != lastGroup {
+			if g >= 0 {
+				fmt.Printf("\nMajor group %d:\n", g)
+			} else {
+				fmt.Println("\nOther files:")
+			}
+		}
+		lastGroup = g
+
+		for decision == 0 {
+			switch a := ask(in, fmt.Sprintf("%s => %s", r.OldName, r.NewName), "ynegsaq?"); a {
+			case 'e':
+				r.NewName = editName(r, in)
+				decision = 'y'
+			case 'g', 's':
+				decision = map[byte]byte{'g': 'y', 's': 'n'}[a]
+				groupDecisions[g] = decision
+			case 'a', 'q':
+				decision = map[byte]byte{'a': 'y', 'q': 'n'}[a]
+				remaining = decision
+			case '?':
+				fmt.Println(reviewHelp)
+			default:
+				decision = a
+			}
+		}
+		if decision == 'y' {
+			reviewed = append(reviewed, r)
+		}
+	}
+	return reviewed
+}
+
+// editName asks for a new name for a file, which must be a name within the directory that is safe on common
+// filesystems.  An empty answer keeps the proposed name.
+func editName(r dirnum.RenameEntry, in *bufio.Reader) string {
+	for {
+		fmt.Printf("New name for %s [%s]: ", r.OldName, r.NewName)
+		name := readLine(in)
+		if name == "" {
+			return r.NewName
+		}
+		if filepath.Base(name) != name {
+			fmt.Println("The new name must be within the same directory")
+		} else if err := dirnum.CheckPortableName(name); err != nil {
+			fmt.Printf("Name %v\n", err)
+		} else {
+			return name
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/beckbria/dirnum/dirnum"
+	"github.com/stretchr/testify/assert"
+)
+
+func reviewWorkspace(t *testing.T, files ...string) *workspace {
+	dir := t.TempDir()
+	for _, f := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, f), nil, 0644))
+	}
+	s := newSettings(flag.NewFlagSet("test", flag.ContinueOnError))
+	s.parse([]string{dir})
+	return s.resolve()
+}
+
+func answers(lines ...string) *bufio.Reader {
+	return bufio.NewReader(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+}
+
+func TestReviewRenames(t *testing.T) {
+	ws := reviewWorkspace(t, "0.jpg", "2-0.jpg", "2-2.jpg", "4-0.jpg", "4-2.jpg", "6.jpg")
+	ren := []dirnum.RenameEntry{
+		{OldName: "2-0.jpg", NewName: "1-0.jpg"},
+		{OldName: "2-2.jpg", NewName: "1-1.jpg"},
+		{OldName: "4-0.jpg", NewName: "2-0.jpg"},
+		{OldName: "4-2.jpg", NewName: "2-1.jpg"},
+		{OldName: "6.jpg", NewName: "3.jpg"},
+	}
+
+	// Accept group 2, skip group 4 and edit the last name
+	reviewed := reviewRenames(ws, ren, answers("g", "s", "e", "3-cat.jpg", "a"))
+	assert.Equal(t, []dirnum.RenameEntry{
+		{OldName: "2-0.jpg", NewName: "1-0.jpg"},
+		{OldName: "2-2.jpg", NewName: "1-1.jpg"},
+		{OldName: "6.jpg", NewName: "3-cat.jpg"},
+	}, reviewed)
+
+	// Skipping the rename which frees 2-0.jpg leaves a collision, so the plan cannot be applied until it is reviewed
+	// again
+	reviewed = reviewRenames(ws, ren, answers("n", "a", "a", "r", "q", "c"))
+	assert.Nil(t, reviewed)
+
+	// Edited names must stay within the directory and be safe
+	reviewed = reviewRenames(ws, ren[4:], answers("e", "sub/3.jpg", "3?.jpg", "", "a"))
+	assert.Equal(t, ren[4:], reviewed)
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beckbria/dirnum/dirnum"
+)
+
+// indexHTML is the browsing UI, which uses the JSON API
+//
+//go:embed serve.html
+var indexHTML string
+
+// planLifetime is how long a confirmation token for a proposed plan remains valid
+const planLifetime = 10 * time.Minute
+
+// server serves a browsing UI and a JSON API over a directory and the directories below it.  Read-only calls are
+// GETs.  Calls which change files are two steps: POST /api/plan computes a plan and returns it with a single-use
+// confirmation token, and POST /api/apply applies the plan the token was issued for, provided the directory has
+// not changed in the meantime.
+type server struct {
+	s           *settings // The flags, which are applied to every directory opened
+	root        string
+	allowRemote bool // Accept requests for any host name rather than only loopback ones
+
+	mu    sync.Mutex
+	plans map[string]*pendingPlan // Keyed by confirmation token
+}
+
+// pendingPlan is a plan which has been shown to the user and awaits confirmation
+type pendingPlan struct {
+	dir       string
+	operation string
+	renames   []dirnum.RenameEntry
+	export    map[string][]string
+	files     []string // The files of the directory when the plan was made
+	expires   time.Time
+}
+
+// planRequest is the body of POST /api/plan
+type planRequest struct {
+	Dir       string `json:"dir"`
+	Operation string `json:"operation"` // "renumber", "fix", "append", "split" or "export"
+	From      string `json:"from"`      // The version to append or split from
+	To        string `json:"to"`        // The version to append onto or split to
+}
+
+// planResponse describes a proposed plan.  Token is empty when there is nothing to do.
+type planResponse struct {
+	Operation string               `json:"operation"`
+	Renames   []dirnum.RenameEntry `json:"renames,omitempty"`
+	Export    map[string][]string  `json:"export,omitempty"`
+	Token     string               `json:"token,omitempty"`
+	Expires   *time.Time           `json:"expires,omitempty"`
+}
+
+// fileEntry is a file shown in a major group, with the validation errors concerning it
+type fileEntry struct {
+	Name   string                   `json:"name"`
+	Errors []dirnum.ValidationError `json:"errors,omitempty"`
+}
+
+// majorGroup is the files sharing a major number.  Major is nil for files which do not match the naming schema.
+type majorGroup struct {
+	Major *int        `json:"major"`
+	Files []fileEntry `json:"files"`
+}
+
+// tagStat counts the uses of a tag
+type tagStat struct {
+	Tag    string `json:"tag"`
+	Count  int    `json:"count"`
+	Majors []int  `json:"majors"`
+}
+
+func runServe(args []string) int {
+	fs := newFlagSet("serve")
+	s := newSettings(fs)
+	s.baselineFlag()
+	s.exportFlags("export-")
+	addr := fs.String("addr", "localhost:8080", "Address to listen on")
+	allowRemote := fs.Bool("allow-remote", false, "Allow listening on an address other than localhost, which lets anyone who can reach it rename files")
+	s.parse(args)
+	ws := s.resolve()
+
+	host, _, err := net.SplitHostPort(*addr)
+	if err != nil {
+		usageError(fmt.Errorf("-addr: %w", err))
+	}
+	if !*allowRemote && !isLoopback(host) {
+		usageError(fmt.Errorf("-addr %s is not a localhost address; pass -allow-remote to listen on it anyway", *addr))
+	}
+	srv := &server{s: s, root: ws.dir, allowRemote: *allowRemote, plans: make(map[string]*pendingPlan)}
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Printf("Serving %s at http://%s/\n", ws.dir, listener.Addr())
+	if err := http.Serve(listener, srv.handler()); err != nil {
+		fatal(err)
+	}
+	return ExitOK
+}
+
+// isLoopback reports whether a host name or address refers to the local machine only
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (srv *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", srv.handleIndex)
+	mux.HandleFunc("GET /api/dirs", srv.handleDirs)
+	mux.HandleFunc("GET /api/validate", srv.handleValidate)
+	mux.HandleFunc("GET /api/groups", srv.handleGroups)
+	mux.HandleFunc("GET /api/stats", srv.handleStats)
+	mux.HandleFunc("GET /api/thumbnail", srv.handleThumbnail)
+	mux.HandleFunc("POST /api/plan", srv.handlePlan)
+	mux.HandleFunc("POST /api/apply", srv.handleApply)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Checking the host name defeats DNS rebinding, in which a remote page resolves its own name to 127.0.0.1
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = strings.Trim(r.Host, "[]") // No port
+		}
+		if !srv.allowRemote && !isLoopback(host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("requests for host %q are not accepted", r.Host))
+			return
+		}
+		// Requiring a JSON body forces a CORS preflight, which is never granted, for calls from other origins
+		if r.Method == http.MethodPost && r.Header.Get("Content-Type") != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, errors.New("requests must be application/json"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// open opens the directory named by a slash-separated path relative to the root, which may not leave it
+func (srv *server) open(rel string) (*workspace, error) {
+	dir := filepath.Join(srv.root, filepath.FromSlash(rel))
+	if r, err := filepath.Rel(srv.root, dir); err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("directory %q is outside %s", rel, srv.root)
+	}
+	return srv.s.open(dir)
+}
+
+// workspace opens the directory given by the "dir" query parameter, writing an error response if it cannot
+func (srv *server) workspace(w http.ResponseWriter, r *http.Request) *workspace {
+	ws, err := srv.open(r.URL.Query().Get("dir"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil
+	}
+	return ws
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func (srv *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, indexHTML)
+}
+
+// handleDirs lists the subdirectories of a directory, so that a library can be browsed
+func (srv *server) handleDirs(w http.ResponseWriter, r *http.Request) {
+	if ws := srv.workspace(w, r); ws != nil {
+		writeJSON(w, map[string]any{"subdirectories": nonNil(ws.dirs)})
+	}
+}
+
+func (srv *server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	ws := srv.workspace(w, r)
+	if ws == nil {
+		return
+	}
+	errs, unused, err := ws.check()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, map[string]any{"errors": errs.All(), "unused": nonNil(unused)})
+}
+
+// handleGroups lists the files of a directory by major group, in numbering order, with their validation errors
+func (srv *server) handleGroups(w http.ResponseWriter, r *http.Request) {
+	ws := srv.workspace(w, r)
+	if ws == nil {
+		return
+	}
+	errs, _, err := ws.check()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	files := append(slices.Clone(ws.files), ws.groupFiles...)
+	groups := []majorGroup{}
+	numbered := make(map[string]bool)
+	for _, f := range ws.schema.ParseFileNames(files) {
+		if len(groups) == 0 || *groups[len(groups)-1].Major != f.Major {
+			major := f.Major
+			groups = append(groups, majorGroup{Major: &major})
+		}
+		g := &groups[len(groups)-1]
+		g.Files = append(g.Files, fileEntry{Name: f.OriginalName, Errors: errs[f.OriginalName]})
+		numbered[f.OriginalName] = true
+	}
+	other := majorGroup{}
+	for _, f := range files {
+		if !numbered[f] {
+			other.Files = append(other.Files, fileEntry{Name: f, Errors: errs[f]})
+		}
+	}
+	if len(other.Files) > 0 {
+		groups = append(groups, other)
+	}
+	writeJSON(w, groups)
+}
+
+func (srv *server) handleStats(w http.ResponseWriter, r *http.Request) {
+	ws := srv.workspace(w, r)
+	if ws == nil {
+		return
+	}
+	computed := ws.schema.ComputeStats(ws.files)
+	if ws.cfg.Stats.Sort == "freq" {
+		dirnum.SortStatsByFrequency(computed)
+	} else {
+		dirnum.SortStatsAlphabetical(computed)
+	}
+	stats := make([]tagStat, len(computed))
+	for i, s := range computed {
+		stats[i] = tagStat{Tag: s.Tag, Count: len(s.Files), Majors: s.Majors()}
+	}
+	writeJSON(w, stats)
+}
+
+// handleThumbnail renders a PNG thumbnail of one of the files of a directory
+func (srv *server) handleThumbnail(w http.ResponseWriter, r *http.Request) {
+	ws := srv.workspace(w, r)
+	if ws == nil {
+		return
+	}
+	file := r.URL.Query().Get("file")
+	if !slices.Contains(ws.files, file) && !slices.Contains(ws.groupFiles, file) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no file %q", file))
+		return
+	}
+	size := dirnum.DefaultContactSheetOptions.ThumbSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		var err error
+		if size, err = strconv.Atoi(v); err != nil || size <= 0 || size > 1024 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid size %q", v))
+			return
+		}
+	}
+	thumb, err := dirnum.ThumbnailFile(filepath.Join(ws.dir, file), size)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	png.Encode(w, thumb)
+}
+
+// handlePlan computes a plan and issues a confirmation token for it
+func (srv *server) handlePlan(w http.ResponseWriter, r *http.Request) {
+	var req planRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ws, err := srv.open(req.Dir)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	plan, err := computePlan(ws, req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	resp := planResponse{Operation: plan.operation, Renames: plan.renames, Export: plan.export}
+	if len(plan.renames) > 0 || len(plan.export) > 0 {
+		token, err := newToken()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		plan.expires = time.Now().Add(planLifetime)
+		srv.mu.Lock()
+		for t, p := range srv.plans {
+			if time.Now().After(p.expires) {
+				delete(srv.plans, t)
+			}
+		}
+		srv.plans[token] = plan
+		srv.mu.Unlock()
+		resp.Token, resp.Expires = token, &plan.expires
+	}
+	writeJSON(w, resp)
+}
+
+// computePlan computes the plan for an operation without applying it
+func computePlan(ws *workspace, req planRequest) (*pendingPlan, error) {
+	plan := &pendingPlan{dir: ws.dir, operation: req.Operation, files: ws.files}
+	var err error
+	switch req.Operation {
+	case "renumber":
+		_, unused := ws.validateNames(ws.files)
+		plan.renames = ws.schema.ComputeRenames(ws.renumberable(ws.files), unused)
+	case "fix":
+		plan.renames = ws.schema.ComputeFixes(ws.files)
+	case "append", "split":
+		var from, to []int
+		if from, err = dirnum.ParseVersion(req.From); err != nil {
+			return nil, err
+		}
+		if to, err = dirnum.ParseVersion(req.To); err != nil {
+			return nil, err
+		}
+		if req.Operation == "append" {
+			plan.operation = fmt.Sprintf("append %s onto %s", req.From, req.To)
+			plan.renames, err = ws.schema.ComputeAppendVersion(ws.files, from, to)
+		} else {
+			plan.operation = fmt.Sprintf("split %s to %s", req.From, req.To)
+			plan.renames, err = ws.schema.ComputeSplit(ws.files, from, to)
+		}
+	case "export":
+		plan.export = ws.schema.PlanExport(ws.files, ws.cfg.Export.Prefix, ws.cfg.Export.MinCount)
+		if conflicts := dirnum.ExportConflicts(ws.dir, plan.export); len(conflicts) > 0 {
+			err = fmt.Errorf("the following matching subdirectories already exist: %s", strings.Join(conflicts, ", "))
+		}
+	default:
+		err = fmt.Errorf("unknown operation %q", req.Operation)
+	}
+	return plan, err
+}
+
+// newToken returns a random, unguessable confirmation token
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// handleApply applies the plan a confirmation token was issued for.  Each token can be used only once.
+func (srv *server) handleApply(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	plan, found := srv.plans[req.Token]
+	delete(srv.plans, req.Token)
+	if !found || time.Now().After(plan.expires) {
+		writeError(w, http.StatusForbidden, errors.New("unknown or expired confirmation token; preview the plan again"))
+		return
+	}
+
+	ws, err := srv.s.open(plan.dir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	lock, err := dirnum.LockDir(ws.dir, plan.operation)
+	if errors.Is(err, dirnum.ErrLocked) {
+		srv.plans[req.Token] = plan // The plan may be applied once the directory is free
+		writeError(w, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer lock.Unlock()
+	if !slices.Equal(ws.files, plan.files) {
+		writeError(w, http.StatusConflict, errors.New("the directory has changed since the plan was made; preview the plan again"))
+		return
+	}
+	if plan.export != nil {
+		err = dirnum.ExportTags(ws.dir, plan.export, func(tag, f string) {})
+	} else {
+		var journal *dirnum.Journal
+		if journal, err = dirnum.ReadJournal(ws.dir); err == nil {
+			journal.Git = ws.git
+			err = journal.Apply(plan.operation, plan.renames)
+		}
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, map[string]any{"operation": plan.operation, "renames": len(plan.renames), "exported": len(plan.export)})
+}
+
+// nonNil returns an empty slice in place of nil, so that it is encoded as [] rather than null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
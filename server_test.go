@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/beckbria/dirnum/dirnum"
+	"github.com/stretchr/testify/assert"
+)
+
+func testServer(t *testing.T, files ...string) (*server, string) {
+	dir := t.TempDir()
+	for _, f := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, f), nil, 0644))
+	}
+	s := newSettings(flag.NewFlagSet("test", flag.ContinueOnError))
+	s.exportFlags("export-")
+	s.parse([]string{dir})
+	return &server{s: s, root: dir, plans: make(map[string]*pendingPlan)}, dir
+}
+
+// call makes a request to the server and decodes the JSON response into result
+func call(t *testing.T, srv *server, method, target, body string, result any) int {
+	r := httptest.NewRequest(method, "http://localhost"+target, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	srv.handler().ServeHTTP(w, r)
+	if result != nil {
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), result), w.Body.String())
+	}
+	return w.Code
+}
+
+func TestServerPlanAndApply(t *testing.T) {
+	srv, dir := testServer(t, "0.jpg", "2.jpg", "3-beach.jpg")
+
+	var groups []majorGroup
+	assert.Equal(t, http.StatusOK, call(t, srv, "GET", "/api/groups", "", &groups))
+	assert.Len(t, groups, 3)
+
+	var plan planResponse
+	assert.Equal(t, http.StatusOK, call(t, srv, "POST", "/api/plan", `{"operation": "renumber"}`, &plan))
+	assert.Equal(t, []dirnum.RenameEntry{{OldName: "3-beach.jpg", NewName: "1-beach.jpg"}}, plan.Renames)
+	assert.NotEmpty(t, plan.Token)
+
+	apply := `{"token": "` + plan.Token + `"}`
+	assert.Equal(t, http.StatusOK, call(t, srv, "POST", "/api/apply", apply, nil))
+	files, err := dirnum.ReadFileNames(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0.jpg", "1-beach.jpg", "2.jpg"}, files)
+
+	// Tokens can be used only once
+	assert.Equal(t, http.StatusForbidden, call(t, srv, "POST", "/api/apply", apply, nil))
+
+	journal, err := dirnum.ReadJournal(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "renumber", journal.Last().Operation)
+}
+
+func TestServerRefusesDriftAndUnsafeRequests(t *testing.T) {
+	srv, dir := testServer(t, "0.jpg", "2.jpg")
+
+	var plan planResponse
+	call(t, srv, "POST", "/api/plan", `{"operation": "append", "from": "2", "to": "0"}`, &plan)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "5.jpg"), nil, 0644))
+	assert.Equal(t, http.StatusConflict, call(t, srv, "POST", "/api/apply", `{"token": "`+plan.Token+`"}`, nil))
+
+	// Form posts, which other sites can send without a preflight, are refused
+	r := httptest.NewRequest("POST", "http://localhost/api/plan", strings.NewReader("operation=renumber"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.handler().ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	// Requests naming another host, as after DNS rebinding, are refused
+	r = httptest.NewRequest("GET", "/api/stats", nil)
+	r.Host = "attacker.example:8080"
+	w = httptest.NewRecorder()
+	srv.handler().ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	assert.Equal(t, http.StatusBadRequest, call(t, srv, "GET", "/api/dirs?dir=..", "", nil))
+	assert.Equal(t, http.StatusNotFound, call(t, srv, "GET", "/api/thumbnail?file=../secret.jpg", "", nil))
+}
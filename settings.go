@@ -0,0 +1,371 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/beckbria/dirnum/dirnum"
+)
+
+// settings registers the flags which correspond to configuration file settings and resolves them against the
+// configuration files: flags given explicitly override the files, which override the defaults.
+type settings struct {
+	fs        *flag.FlagSet
+	dir       *string
+	args      []string // Positional arguments
+	overrides map[string]func(*dirnum.Config)
+}
+
+func newSettings(fs *flag.FlagSet) *settings {
+	s := &settings{fs: fs, overrides: make(map[string]func(*dirnum.Config))}
+	s.dir = fs.String("dir", "", "The directory to analyze; may also be given as the first argument")
+	defaults := dirnum.DefaultConfig()
+	s.boolFlag("ignoremajor", defaults.IgnoreMajor, "Do not print warnings for skips in the major version numbering",
+		func(c *dirnum.Config, v bool) { c.IgnoreMajor = v })
+	s.boolFlag("ignoreminorzero", defaults.IgnoreMinorZero, "Do not print warnings for minor numbering skipping zero",
+		func(c *dirnum.Config, v bool) { c.IgnoreMinorZero = v })
+	s.stringFlag("ignore", "", "Comma-separated patterns, in the syntax of "+dirnum.IgnoreFileName+", of file names to skip",
+		func(c *dirnum.Config, v string) { c.Ignore = splitList(v) })
+	s.boolFlag("group-dirs", defaults.GroupDirs, "Validate subdirectories named by a major number, such as 0012, as holding that major group",
+		func(c *dirnum.Config, v bool) { c.GroupDirs = v })
+	s.boolFlag("git", defaults.Git, "Rename tracked files with 'git mv', refusing to rename files with uncommitted changes",
+		func(c *dirnum.Config, v bool) { c.Git = v })
+	s.stringFlag("schema", defaults.Schema.Template, "Template describing how file names are laid out, e.g. 'IMG-{major}[_{minor}][ {descriptor}].{extension}'",
+		func(c *dirnum.Config, v string) { c.Schema.Template = v })
+	s.stringFlag("schema-pattern", defaults.Schema.Pattern, "Regular expression with named groups to parse file names instead of the one derived from -schema",
+		func(c *dirnum.Config, v string) { c.Schema.Pattern = v })
+	s.stringFlag("extensions", strings.Join(defaults.Schema.Extensions, ","), "Comma-separated list of accepted file extensions",
+		func(c *dirnum.Config, v string) { c.Schema.Extensions = splitList(v) })
+	s.stringFlag("descriptor-chars", defaults.Schema.DescriptorChars, "Characters allowed in descriptors after the first letter, as the contents of a regular expression character class",
+		func(c *dirnum.Config, v string) { c.Schema.DescriptorChars = v })
+	s.stringFlag("descriptor-style", string(defaults.Schema.DescriptorStyle), "How descriptors are delimited: 'plain' (0001-beach.jpg), 'dashes' (0001--2019.jpg) or 'brackets' (0001 [2019].jpg)",
+		func(c *dirnum.Config, v string) { c.Schema.DescriptorStyle = dirnum.DescriptorStyle(v) })
+	s.majorsFlag("reserved", "Comma-separated major numbers or ranges, such as 7,40-49, which are set aside: never reported as gaps nor filled by renumbering",
+		func(c *dirnum.Config, v []int) { c.Schema.Reserved = v })
+	s.majorsFlag("frozen", "Comma-separated major numbers or ranges of groups which are never renumbered, moved or appended to",
+		func(c *dirnum.Config, v []int) { c.Schema.Frozen = v })
+	return s
+}
+
+// checkFlags registers the flags which control how validation errors fail a check
+func (s *settings) checkFlags() {
+	defaults := dirnum.DefaultConfig()
+	s.stringFlag("fail-on", strings.Join(defaults.Check.FailOn, ","), "Comma-separated classes of error which fail the check: 'invalid' and/or 'fixable'",
+		func(c *dirnum.Config, v string) { c.Check.FailOn = splitList(v) })
+	s.stringFlag("fail-severity", string(defaults.Check.FailSeverity), "Minimum severity of error which fails the check: 'warning' or 'error'",
+		func(c *dirnum.Config, v string) { c.Check.FailSeverity = dirnum.Severity(v) })
+	s.baselineFlag()
+}
+
+// baselineFlag registers the flag which selects the baseline file
+func (s *settings) baselineFlag() {
+	s.stringFlag("baseline", dirnum.DefaultConfig().Check.Baseline, "Baseline file of accepted errors to suppress: a path, 'auto' to use "+dirnum.BaselineFileName+" in the directory if present, or 'none'",
+		func(c *dirnum.Config, v string) { c.Check.Baseline = v })
+}
+
+// exportFlags registers the flags which control exporting tags
+func (s *settings) exportFlags(prefix string) {
+	defaults := dirnum.DefaultConfig()
+	s.stringFlag(prefix+"prefix", defaults.Export.Prefix, "Optional prefix to filter tags for export",
+		func(c *dirnum.Config, v string) { c.Export.Prefix = v })
+	s.intFlag(prefix+"min-count", defaults.Export.MinCount, "Only export tags that appear at least this many times",
+		func(c *dirnum.Config, v int) { c.Export.MinCount = v })
+}
+
+// statsFlags registers the flags which control tag statistics
+func (s *settings) statsFlags(prefix string) {
+	defaults := dirnum.DefaultConfig()
+	s.stringFlag(prefix+"sort", defaults.Stats.Sort, "Sort order for stats: 'alpha' (alphabetical) or 'freq' (frequency)",
+		func(c *dirnum.Config, v string) { c.Stats.Sort = v })
+	s.boolFlag(prefix+"names", defaults.Stats.Names, "Print tags and the major versions where they appear instead of counts",
+		func(c *dirnum.Config, v bool) { c.Stats.Names = v })
+}
+
+func (s *settings) boolFlag(name string, value bool, usage string, apply func(*dirnum.Config, bool)) {
+	v := s.fs.Bool(name, value, usage)
+	s.overrides[name] = func(c *dirnum.Config) { apply(c, *v) }
+}
+
+func (s *settings) stringFlag(name string, value string, usage string, apply func(*dirnum.Config, string)) {
+	v := s.fs.String(name, value, usage)
+	s.overrides[name] = func(c *dirnum.Config) { apply(c, *v) }
+}
+
+func (s *settings) intFlag(name string, value int, usage string, apply func(*dirnum.Config, int)) {
+	v := s.fs.Int(name, value, usage)
+	s.overrides[name] = func(c *dirnum.Config) { apply(c, *v) }
+}
+
+// majorsFlag registers a flag holding a list of major numbers, which is parsed along with the command line
+func (s *settings) majorsFlag(name string, usage string, apply func(*dirnum.Config, []int)) {
+	var v []int
+	s.fs.Func(name, usage, func(value string) (err error) {
+		v, err = parseMajors(value)
+		return err
+	})
+	s.overrides[name] = func(c *dirnum.Config) { apply(c, v) }
+}
+
+// parse parses the command line.  Unlike FlagSet.Parse, flags may follow the positional arguments, so that both
+// "validate -fail-on invalid photos" and "validate photos -fail-on invalid" work.
+func (s *settings) parse(args []string) {
+	for {
+		s.fs.Parse(args)
+		if s.fs.NArg() == 0 {
+			return
+		}
+		s.args = append(s.args, s.fs.Arg(0))
+		args = s.fs.Args()[1:]
+	}
+}
+
+// isSet reports whether a flag was given explicitly
+func (s *settings) isSet(name string) bool {
+	set := false
+	s.fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// workspace is a directory together with its effective configuration
+type workspace struct {
+	dir         string
+	cfg         dirnum.Config
+	configPaths []string
+	schema      *dirnum.Schema
+	files       []string        // The files directly within dir, which are the only ones renamed
+	dirs        []string        // The subdirectories of dir, which are never renamed
+	groupFiles  []string        // With GroupDirs, the files within group directories, as paths relative to dir
+	git         *dirnum.GitRepo // With Git, the work tree containing dir
+}
+
+// resolve loads the configuration for the directory, applies the flags and reads the directory.  It exits if the
+// command line or configuration is invalid.
+func (s *settings) resolve() *workspace {
+	dir := *s.dir
+	if dir == "" && len(s.args) > 0 {
+		dir = s.args[0]
+	}
+	if dir == "" || len(s.args) > 1 || (*s.dir != "" && len(s.args) > 0) {
+		fmt.Fprintf(os.Stderr, "Exactly one directory is required\n")
+		s.fs.Usage()
+		os.Exit(ExitUsage)
+	}
+
+	ws, err := s.open(dir)
+	var usage usageErr
+	if errors.As(err, &usage) {
+		usageError(usage.error)
+	} else if err != nil {
+		fatal(err)
+	}
+	return ws
+}
+
+// usageErr marks an error caused by the command line rather than by the directory
+type usageErr struct{ error }
+
+// open loads the configuration for a directory, applies the flags and reads the directory.  Errors caused by the
+// flags are usageErrs.
+func (s *settings) open(dir string) (*workspace, error) {
+	cfg, configPaths, err := dirnum.LoadConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	s.fs.Visit(func(f *flag.Flag) {
+		if apply, found := s.overrides[f.Name]; found {
+			apply(&cfg)
+		}
+	})
+	if err := cfg.Validate(); err != nil {
+		return nil, usageErr{err}
+	}
+	schema, err := dirnum.NewSchema(cfg.Schema)
+	if err != nil {
+		return nil, usageErr{err}
+	}
+
+	ws := &workspace{dir: dir, cfg: cfg, configPaths: configPaths, schema: schema}
+	if cfg.Git {
+		if ws.git, err = dirnum.OpenGitRepo(dir); err != nil {
+			return nil, usageErr{err}
+		}
+	}
+	return ws, ws.read()
+}
+
+// reload rereads the names of the files in the directory, e.g. after renaming them
+func (ws *workspace) reload() {
+	if err := ws.read(); err != nil {
+		fatal(err)
+	}
+}
+
+// read reads the names of the files in the directory
+func (ws *workspace) read() error {
+	files, dirs, err := dirnum.ReadEntries(ws.dir, ws.cfg.Ignore...)
+	if err != nil {
+		return err
+	}
+	ws.files, ws.dirs, ws.groupFiles = files, dirs, nil
+	if ws.cfg.GroupDirs {
+		if ws.groupFiles, err = dirnum.ReadGroupFiles(ws.dir, dirs, ws.cfg.Ignore...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateNames validates files, which are names within the directory such as a proposed renaming of ws.files,
+// together with the files within group directories.  It returns the errors found and the unused major numbers, which
+// excludes those of group directories.
+func (ws *workspace) validateNames(files []string) (dirnum.ValidationErrors, []int) {
+	all := append(slices.Clone(files), ws.groupFiles...)
+	return ws.schema.ValidateFileNames(all, ws.cfg.IgnoreMajor, ws.cfg.IgnoreMinorZero)
+}
+
+// renumberable removes from files, which are names within the directory, those of major groups which also have a
+// group directory.  Their versions are shared with the files in the group directory, which renumbering does not
+// rename, so renumbering them alone could give a file the version of one in the group directory.
+func (ws *workspace) renumberable(files []string) []string {
+	if !ws.cfg.GroupDirs {
+		return files
+	}
+	grouped := make(map[int]bool)
+	for _, d := range ws.dirs {
+		if major, isGroup := dirnum.GroupDirectoryMajor(d); isGroup {
+			grouped[major] = true
+		}
+	}
+	return slices.DeleteFunc(slices.Clone(files), func(f string) bool {
+		name, err := ws.schema.Parse(f)
+		return err == nil && grouped[name.Major]
+	})
+}
+
+// otherDirs lists the subdirectories which are not validated as group directories
+func (ws *workspace) otherDirs() []string {
+	var other []string
+	for _, d := range ws.dirs {
+		if _, isGroup := dirnum.GroupDirectoryMajor(d); !isGroup || !ws.cfg.GroupDirs {
+			other = append(other, d)
+		}
+	}
+	return other
+}
+
+// validate validates the directory, adds any errors found by additional checks, and suppresses errors accepted by
+// the configured baseline.  It also returns the unused major numbers.
+func (ws *workspace) validate(extra ...dirnum.ValidationErrors) (dirnum.ValidationErrors, []int) {
+	errors, unused, err := ws.check(extra...)
+	if err != nil {
+		fatal(err)
+	}
+	return errors, unused
+}
+
+// check is validate, returning an error if the baseline cannot be read rather than exiting
+func (ws *workspace) check(extra ...dirnum.ValidationErrors) (dirnum.ValidationErrors, []int, error) {
+	errors, unused := ws.validateNames(ws.files)
+	for _, e := range extra {
+		errors.Merge(e)
+	}
+	if path := ws.baselinePath(); path != "none" {
+		if _, err := os.Stat(path); err == nil || ws.cfg.Check.Baseline != "auto" {
+			b, err := dirnum.ReadBaseline(path)
+			if err != nil {
+				return nil, nil, err
+			}
+			errors = b.Filter(errors)
+		}
+	}
+	// Files left behind by an interrupted rename are always reported, as they cannot be accepted
+	interrupted, err := dirnum.FindInterruptedRenames(ws.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	errors.Merge(interrupted)
+	return errors, unused, nil
+}
+
+// heldLock is the directory lock held by this process, if any, so that exiting early can release it
+var heldLock *dirnum.DirLock
+
+// lock acquires the directory's lock for an operation which changes it, exiting if another process holds it.  It
+// is released by releaseLock.
+func (ws *workspace) lock(operation string) {
+	l, err := dirnum.LockDir(ws.dir, operation)
+	if err != nil {
+		fatal(err)
+	}
+	heldLock = l
+}
+
+// releaseLock releases the directory lock, if it is held
+func releaseLock() {
+	if heldLock == nil {
+		return
+	}
+	if err := heldLock.Unlock(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	heldLock = nil
+}
+
+// journal reads the directory's journal, which applies renames with git if configured
+func (ws *workspace) journal() *dirnum.Journal {
+	journal, err := dirnum.ReadJournal(ws.dir)
+	if err != nil {
+		fatal(err)
+	}
+	journal.Git = ws.git
+	return journal
+}
+
+// baselinePath returns the path of the configured baseline file, or "none"
+func (ws *workspace) baselinePath() string {
+	if ws.cfg.Check.Baseline == "auto" {
+		return filepath.Join(ws.dir, dirnum.BaselineFileName)
+	}
+	return ws.cfg.Check.Baseline
+}
+
+// Splits a comma-separated flag value into its trimmed, non-empty elements
+func splitList(s string) []string {
+	list := []string{}
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// parseMajors parses a comma-separated list of major numbers and inclusive ranges of them, such as "7,40-49"
+func parseMajors(s string) ([]int, error) {
+	majors := []int{}
+	for _, e := range splitList(s) {
+		first, last, isRange := strings.Cut(e, "-")
+		lo, err := strconv.Atoi(first)
+		hi := lo
+		if err == nil && isRange {
+			hi, err = strconv.Atoi(last)
+		}
+		if err != nil || lo < 0 || hi < lo {
+			return nil, fmt.Errorf("invalid major number or range %q", e)
+		}
+		for n := lo; n <= hi; n++ {
+			majors = append(majors, n)
+		}
+	}
+	return majors, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/beckbria/dirnum/dirnum"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSettingsFlagsOverrideConfig(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, dirnum.ConfigFileName),
+		[]byte(`{"ignoreMajor": false, "stats": {"sort": "freq"}, "export": {"prefix": "person"}}`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "0.jpg"), nil, 0644))
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	s := newSettings(fs)
+	s.statsFlags("")
+	s.exportFlags("")
+	// Flags may follow the directory
+	s.parse([]string{"-sort", "alpha", dir, "-ignore", "*.txt"})
+	ws := s.resolve()
+
+	assert.Equal(t, dir, ws.dir)
+	assert.False(t, ws.cfg.IgnoreMajor)
+	assert.Equal(t, "alpha", ws.cfg.Stats.Sort)
+	assert.Equal(t, "person", ws.cfg.Export.Prefix)
+	assert.Equal(t, []string{"*.txt"}, ws.cfg.Ignore)
+	assert.Equal(t, []string{"0.jpg"}, ws.files)
+}
+
+func TestReservedAndFrozenFlags(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, dirnum.ConfigFileName), []byte(`{"schema": {"frozen": [1]}}`), 0644))
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	s := newSettings(fs)
+	s.parse([]string{"-reserved", "7, 40-42", dir})
+	ws := s.resolve()
+	assert.Equal(t, []int{7, 40, 41, 42}, ws.cfg.Schema.Reserved)
+	assert.Equal(t, []int{1}, ws.cfg.Schema.Frozen)
+	assert.True(t, ws.schema.IsReserved(41))
+	assert.True(t, ws.schema.IsFrozen(1))
+
+	for _, bad := range []string{"x", "-1", "5-3", "1-"} {
+		_, err := parseMajors(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestRenumberSkipsGroupDirectories(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, dirnum.ConfigFileName), []byte(`{"groupDirs": true}`), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "3"), 0755))
+	for _, f := range []string{"0.jpg", "1-0.jpg", "1-5.jpg", "2.jpg", "3-0.jpg", "3-5.jpg", "3/3-1.jpg"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, f), nil, 0644))
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	s := newSettings(fs)
+	s.parse([]string{dir})
+	ws := s.resolve()
+	_, unused := ws.validateNames(ws.files)
+	// 3-5.jpg is not renamed to 3-1.jpg, which the group directory already holds
+	assert.Equal(t, []dirnum.RenameEntry{{OldName: "1-5.jpg", NewName: "1-1.jpg"}},
+		ws.schema.ComputeRenames(ws.renumberable(ws.files), unused))
+}